@@ -0,0 +1,313 @@
+// Package combinator contains generic parser combinators. A Parser reads the start of an Input and returns the rest of
+// the input together with the value it read, so small parsers can be combined into the parser of a whole grammar
+// without passing results through out parameters.
+//
+// When a parser fails it calls Fail with a description of what it expected. The State of the input remembers what was
+// expected at the furthest position at which any parser failed. Since parsers backtrack, that position is usually where
+// the actual mistake is, so errors point there and list everything that would have been accepted.
+package combinator
+
+import "strings"
+
+// Input is the code which is read by the parsers. Reading never changes an Input, instead a new Input for the rest of
+// the code is returned.
+type Input[I any] interface {
+	// Index returns how far the code was read, e.g. the index of the next token.
+	Index() int
+	// State returns the State which is shared by every Input of the same code.
+	State() *State[I]
+	// Describe describes the start of the input for error messages, e.g. "'{'" or "end of the code".
+	Describe() string
+	// Error creates an error with the message which points at the start of the input.
+	Error(message string) error
+}
+
+// Parser reads the start of the input. It returns the rest of the input and the value which was read. If it fails,
+// the input is returned unchanged together with an error.
+type Parser[I Input[I], T any] func(I) (I, T, error)
+
+// State is shared by every Input of the same code and remembers the furthest failure and the results of memoized
+// parsers.
+type State[I any] struct {
+	furthest I
+	index    int      // the index of furthest
+	expected []string // what the parsers which failed at furthest expected, e.g. "')'" or "operator"
+	memo     map[memoKey]interface{}
+}
+
+type memoKey struct {
+	rule  string
+	index int
+}
+
+// result is what a memoized parser returned.
+type result[I any, T any] struct {
+	rest  I
+	value T
+	err   error
+}
+
+// Error is the error of a parser which failed. Most failures are caught by other parsers which backtrack, so the error
+// of the Input is only created when it's needed. It always describes the furthest failure at that time.
+type Error[I Input[I]] struct {
+	state *State[I]
+}
+
+func (e Error[I]) Error() string {
+	return e.Unwrap().Error()
+}
+
+// Unwrap returns the error created by the Input for the furthest failure, e.g. "expected ')' or operator, found '{'".
+func (e Error[I]) Unwrap() error {
+	s := e.state
+	// specific tokens are listed before descriptions like "operator"
+	expected := []string{}
+	for _, quoted := range []bool{true, false} {
+		for _, e := range s.expected {
+			if strings.HasPrefix(e, "'") == quoted {
+				expected = append(expected, e)
+			}
+		}
+	}
+	if len(expected) > 1 {
+		expected = []string{strings.Join(expected[:len(expected)-1], ", "), expected[len(expected)-1]}
+	}
+
+	return s.furthest.Error("expected " + strings.Join(expected, " or ") + ", found " + s.furthest.Describe())
+}
+
+// Fail records that a parser expected something else at the start of the input, e.g. "')'" or "name". It returns the
+// error for the furthest input at which any parser failed.
+func Fail[I Input[I]](in I, expected string) error {
+	s := in.State()
+	if in.Index() > s.index || in.Index() == s.index && len(s.expected) == 0 {
+		s.furthest, s.index, s.expected = in, in.Index(), nil
+	}
+	if in.Index() == s.index && !contains(s.expected, expected) {
+		s.expected = append(s.expected, expected)
+	}
+	return Error[I]{state: s}
+}
+
+// Succeed returns the value without reading anything.
+func Succeed[I Input[I], T any](value T) Parser[I, T] {
+	return func(in I) (I, T, error) {
+		return in, value, nil
+	}
+}
+
+// Map runs the parser p and converts the value it read with f.
+func Map[I Input[I], A, B any](p Parser[I, A], f func(A) B) Parser[I, B] {
+	return func(in I) (I, B, error) {
+		rest, a, err := p(in)
+		if err != nil {
+			var b B
+			return in, b, err
+		}
+		return rest, f(a), nil
+	}
+}
+
+// Seq runs the parsers one after the other and returns all the values they read. It fails if any of them fails.
+func Seq[I Input[I], T any](parsers ...Parser[I, T]) Parser[I, []T] {
+	return func(in I) (I, []T, error) {
+		values := make([]T, 0, len(parsers))
+		rest := in
+		for _, p := range parsers {
+			var value T
+			var err error
+			rest, value, err = p(rest)
+			if err != nil {
+				return in, nil, err
+			}
+			values = append(values, value)
+		}
+		return rest, values, nil
+	}
+}
+
+// Seq2 runs the parsers a and b one after the other and combines their values with f.
+func Seq2[I Input[I], A, B, T any](a Parser[I, A], b Parser[I, B], f func(A, B) T) Parser[I, T] {
+	return func(in I) (I, T, error) {
+		var t T
+		rest, va, err := a(in)
+		if err != nil {
+			return in, t, err
+		}
+		rest, vb, err := b(rest)
+		if err != nil {
+			return in, t, err
+		}
+		return rest, f(va, vb), nil
+	}
+}
+
+// Seq3 runs the parsers a, b and c one after the other and combines their values with f.
+func Seq3[I Input[I], A, B, C, T any](a Parser[I, A], b Parser[I, B], c Parser[I, C], f func(A, B, C) T) Parser[I, T] {
+	return Seq2(a, Seq2(b, c, pair[B, C]), func(va A, bc tuple[B, C]) T { return f(va, bc.first, bc.second) })
+}
+
+// Seq4 runs the parsers a, b, c and d one after the other and combines their values with f.
+func Seq4[I Input[I], A, B, C, D, T any](a Parser[I, A], b Parser[I, B], c Parser[I, C], d Parser[I, D], f func(A, B, C, D) T) Parser[I, T] {
+	return Seq2(Seq2(a, b, pair[A, B]), Seq2(c, d, pair[C, D]), func(ab tuple[A, B], cd tuple[C, D]) T {
+		return f(ab.first, ab.second, cd.first, cd.second)
+	})
+}
+
+type tuple[A, B any] struct {
+	first  A
+	second B
+}
+
+func pair[A, B any](first A, second B) tuple[A, B] {
+	return tuple[A, B]{first, second}
+}
+
+// Then runs first and then second but only returns the value of second, e.g. for a keyword in front of an expression.
+func Then[I Input[I], A, B any](first Parser[I, A], second Parser[I, B]) Parser[I, B] {
+	return Seq2(first, second, func(_ A, b B) B { return b })
+}
+
+// Skip runs p and then next but only returns the value of p, e.g. for an expression which is followed by a ";".
+func Skip[I Input[I], T, S any](p Parser[I, T], next Parser[I, S]) Parser[I, T] {
+	return Seq2(p, next, func(t T, _ S) T { return t })
+}
+
+// Between runs open, p and close one after the other but only returns the value of p, e.g. for parentheses.
+func Between[I Input[I], O, T, C any](open Parser[I, O], p Parser[I, T], close Parser[I, C]) Parser[I, T] {
+	return Skip(Then(open, p), close)
+}
+
+// Alt returns the value of the first parser which works. If none of them works, the error for the furthest failure is
+// returned.
+func Alt[I Input[I], T any](parsers ...Parser[I, T]) Parser[I, T] {
+	return func(in I) (I, T, error) {
+		for _, p := range parsers {
+			if rest, value, err := p(in); err == nil {
+				return rest, value, nil
+			}
+		}
+		var t T
+		return in, t, Error[I]{state: in.State()}
+	}
+}
+
+// Opt runs p. If p fails, the fallback is returned without reading anything.
+func Opt[I Input[I], T any](p Parser[I, T], fallback T) Parser[I, T] {
+	return func(in I) (I, T, error) {
+		if rest, value, err := p(in); err == nil {
+			return rest, value, nil
+		}
+		return in, fallback, nil
+	}
+}
+
+// Many runs p as often as it works and returns all the values it read. The list can also be empty.
+func Many[I Input[I], T any](p Parser[I, T]) Parser[I, []T] {
+	return func(in I) (I, []T, error) {
+		values := []T{}
+		for {
+			rest, value, err := p(in)
+			// a parser which doesn't read anything would work forever
+			if err != nil || rest.Index() == in.Index() {
+				return in, values, nil
+			}
+			in = rest
+			values = append(values, value)
+		}
+	}
+}
+
+// SepBy runs p repeatedly with sep in between and returns the values read by p. The list can also be empty.
+func SepBy[I Input[I], T, S any](p Parser[I, T], sep Parser[I, S]) Parser[I, []T] {
+	return func(in I) (I, []T, error) {
+		rest, first, err := p(in)
+		if err != nil {
+			return in, []T{}, nil
+		}
+		rest, others, _ := Many(Then(sep, p))(rest)
+		return rest, append([]T{first}, others...), nil
+	}
+}
+
+// ChainL1 reads one or more operands with operators in between. The operators are left associative, so the values are
+// combined from left to right with the functions returned by op, e.g. "1 - 2 - 3" is read as "(1 - 2) - 3".
+func ChainL1[I Input[I], T any](operand Parser[I, T], op Parser[I, func(T, T) T]) Parser[I, T] {
+	return func(in I) (I, T, error) {
+		rest, value, err := operand(in)
+		if err != nil {
+			return in, value, err
+		}
+		for {
+			tmp, combine, err := op(rest)
+			if err != nil {
+				return rest, value, nil
+			}
+			tmp, second, err := operand(tmp)
+			if err != nil {
+				var t T
+				return in, t, err
+			}
+			rest, value = tmp, combine(value, second)
+		}
+	}
+}
+
+// Lookahead runs p but doesn't read anything, so the value of p can be used to decide how to go on.
+func Lookahead[I Input[I], T any](p Parser[I, T]) Parser[I, T] {
+	return func(in I) (I, T, error) {
+		_, value, err := p(in)
+		return in, value, err
+	}
+}
+
+// Memo runs p only once for every position of the input and remembers its result, so backtracking parsers which try
+// the same rule at the same position again don't read the same code twice. This keeps the parsing time linear in the
+// length of the code. The rule has to be a unique name for p since the results are looked up by the rule.
+func Memo[I Input[I], T any](rule string, p Parser[I, T]) Parser[I, T] {
+	return func(in I) (I, T, error) {
+		s := in.State()
+		key := memoKey{rule: rule, index: in.Index()}
+		if r, ok := s.memo[key]; ok {
+			r := r.(result[I, T])
+			return r.rest, r.value, r.err
+		}
+
+		rest, value, err := p(in)
+		if s.memo == nil {
+			s.memo = map[memoKey]interface{}{}
+		}
+		s.memo[key] = result[I, T]{rest: rest, value: value, err: err}
+		return rest, value, err
+	}
+}
+
+// Label runs p. If p fails without getting past the start of the input, everything p expected there is replaced with
+// the name, e.g. all the different kinds of operands are summarized as "expression".
+func Label[I Input[I], T any](name string, p Parser[I, T]) Parser[I, T] {
+	return func(in I) (I, T, error) {
+		s := in.State()
+		index, count := s.index, len(s.expected)
+
+		rest, value, err := p(in)
+		if err == nil || s.index != in.Index() {
+			return rest, value, err
+		}
+
+		if index == in.Index() {
+			s.expected = s.expected[:count]
+		} else {
+			s.expected = nil
+		}
+		return in, value, Fail(in, name)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, elem := range list {
+		if elem == s {
+			return true
+		}
+	}
+	return false
+}
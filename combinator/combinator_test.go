@@ -0,0 +1,166 @@
+package combinator_test
+
+import (
+	"fmt"
+	. "mbs/combinator"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// text is an Input which reads a string one byte after the other.
+type text struct {
+	s     string
+	i     int
+	state *State[text]
+}
+
+func newText(s string) text {
+	return text{s: s, state: &State[text]{}}
+}
+
+func (t text) Index() int {
+	return t.i
+}
+
+func (t text) State() *State[text] {
+	return t.state
+}
+
+func (t text) Describe() string {
+	if t.i == len(t.s) {
+		return "end"
+	}
+	return "'" + t.s[t.i:t.i+1] + "'"
+}
+
+func (t text) Error(message string) error {
+	return fmt.Errorf("%d: %s", t.i, message)
+}
+
+func char(c byte) Parser[text, byte] {
+	return func(t text) (text, byte, error) {
+		if t.i == len(t.s) || t.s[t.i] != c {
+			return t, 0, Fail(t, "'"+string(c)+"'")
+		}
+		return text{s: t.s, i: t.i + 1, state: t.state}, c, nil
+	}
+}
+
+func digit(t text) (text, int, error) {
+	if t.i == len(t.s) || t.s[t.i] < '0' || t.s[t.i] > '9' {
+		return t, 0, Fail(t, "digit")
+	}
+	return text{s: t.s, i: t.i + 1, state: t.state}, int(t.s[t.i] - '0'), nil
+}
+
+// expression reads sums and differences of digits and parenthesized expressions like "1-(2+3)".
+func expression(t text) (text, int, error) {
+	operand := Label("operand", Alt(digit, Between(char('('), expression, char(')'))))
+	operator := Alt(
+		Map(char('+'), func(byte) func(int, int) int { return func(a, b int) int { return a + b } }),
+		Map(char('-'), func(byte) func(int, int) int { return func(a, b int) int { return a - b } }),
+	)
+	return ChainL1(operand, Label("operator", operator))(t)
+}
+
+func TestParsers(t *testing.T) {
+	testCase := func(name string, p Parser[text, int], input string, expected int, expectedRest int) {
+		t.Run(name+" "+input, func(t *testing.T) {
+			rest, value, err := p(newText(input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if value != expected || rest.Index() != expectedRest {
+				t.Errorf("got %d with %d bytes read wanted %d with %d bytes read", value, rest.Index(), expected, expectedRest)
+			}
+		})
+	}
+	count := func(values []int) int {
+		return len(values)
+	}
+	sum := func(values []int) int {
+		result := 0
+		for _, value := range values {
+			result += value
+		}
+		return result
+	}
+
+	testCase("ChainL1", expression, "1-2-3", -4, 5)
+	testCase("ChainL1", expression, "1-(2-3)", 2, 7)
+	testCase("Map", Map(digit, func(d int) int { return d * 10 }), "4", 40, 1)
+	testCase("Seq", Map(Seq(digit, digit, digit), sum), "1234", 6, 3)
+	testCase("Seq2", Seq2(digit, Then(char('.'), digit), func(a, b int) int { return a*10 + b }), "1.5", 15, 3)
+	testCase("Seq3", Seq3(digit, digit, digit, func(a, b, c int) int { return a*100 + b*10 + c }), "123", 123, 3)
+	testCase("Seq4", Seq4(digit, digit, digit, digit, func(a, b, c, d int) int { return a + b + c + d }), "1234", 10, 4)
+	testCase("Skip", Skip(digit, char(';')), "7;", 7, 2)
+	testCase("Opt", Opt(digit, -1), "x", -1, 0)
+	testCase("Opt", Opt(digit, -1), "3", 3, 1)
+	testCase("Many", Map(Many(digit), count), "123x", 3, 3)
+	testCase("Many", Map(Many(digit), count), "x", 0, 0)
+	testCase("SepBy", Map(SepBy(digit, char(',')), sum), "1,2,3", 6, 5)
+	testCase("SepBy", Map(SepBy(digit, char(',')), sum), "1,2,", 3, 3)
+	testCase("SepBy", Map(SepBy(digit, char(',')), count), "", 0, 0)
+	testCase("Lookahead", Lookahead(digit), "5", 5, 0)
+	testCase("Succeed", Succeed[text](42), "", 42, 0)
+}
+
+func TestParsers_errors(t *testing.T) {
+	testCase := func(name string, p Parser[text, int], input string, expected string) {
+		t.Run(name+" "+input, func(t *testing.T) {
+			rest, _, err := p(newText(input))
+			if err == nil || err.Error() != expected {
+				t.Errorf(`got "%v" wanted "%s"`, err, expected)
+			}
+			if rest.Index() != 0 {
+				t.Errorf("got %d bytes read wanted none after an error", rest.Index())
+			}
+		})
+	}
+
+	testCase("Alt", Alt(digit, Map(char('x'), func(byte) int { return 0 })), "y", "0: expected 'x' or digit, found 'y'")
+	// the error points at the furthest failure and lists everything that was expected there
+	testCase("ChainL1", Skip(expression, char(';')), "1-(2+3", "6: expected ')' or operator, found end")
+	testCase("ChainL1", expression, "1-", "2: expected operand, found end")
+	testCase("ChainL1", Skip(expression, char(';')), "1-(2+)", "5: expected operand, found ')'")
+	testCase("Between", Between(char('['), digit, char(']')), "[1", "2: expected ']', found end")
+	testCase("Seq", Map(Seq(digit, digit), func([]int) int { return 0 }), "1x", "1: expected digit, found 'x'")
+	testCase("Lookahead", Lookahead(digit), "x", "0: expected digit, found 'x'")
+}
+
+func TestLabel(t *testing.T) {
+	input := newText("a")
+	// what was expected before the labeled parser started is kept
+	_ = Fail(input, "'b'")
+	_, _, err := Label("number", Alt(digit, Map(char('-'), func(byte) int { return 0 })))(input)
+
+	if diff := cmp.Diff("0: expected 'b' or number, found 'a'", err.Error()); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestMemo(t *testing.T) {
+	calls := map[int]int{}
+	counted := func(t text) (text, int, error) {
+		calls[t.i]++
+		return digit(t)
+	}
+	// both alternatives start with the same digit, which is only read once
+	number := Memo("digit", counted)
+	sum := Alt(
+		Seq2(number, Then(char('+'), number), func(a, b int) int { return a + b }),
+		Seq2(number, Then(char('-'), number), func(a, b int) int { return a - b }),
+	)
+
+	_, value, err := sum(newText("7-2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != 5 {
+		t.Errorf("got %d wanted 5", value)
+	}
+	if diff := cmp.Diff(map[int]int{0: 1, 2: 1}, calls); diff != "" {
+		t.Error(diff)
+	}
+}
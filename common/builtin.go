@@ -0,0 +1,231 @@
+package common
+
+import (
+	"fmt"
+	"mbs/types"
+	"unicode/utf8"
+)
+
+// Builtin is a function which can be called without being declared. The typechecker and the Interpreter both use the
+// same Builtins, so adding a builtin only needs a new entry in builtins.
+type Builtin struct {
+	Name string
+	// Params are the types of the parameters. A parameter is types.Nop if it accepts values of different types, these
+	// are checked by Check.
+	Params []types.Type
+	// Returns is the type of the result, types.Nop if the function doesn't return a value or if the type of the result
+	// depends on the arguments like the list returned by append. Check has to return the type in that case.
+	Returns types.Type
+	// Check is only needed if the types depend on each other, e.g. the element passed to append has to match the
+	// list. It gets the types of the arguments and returns the type of the result, which is Returns unless Returns is
+	// types.Nop.
+	Check func(args []types.Type) (types.Type, *ArgumentError)
+	// Call executes the function with the values of the arguments. The call is passed so errors can point at it.
+	Call func(interp *Interpreter, call FunctionCall, args []interface{}) interface{}
+}
+
+// ArgumentError describes an argument which was passed to a Builtin but has the wrong type.
+type ArgumentError struct {
+	Index       int        // the position of the argument
+	Expected    types.Type // types.Nop if values of different types would be accepted
+	Description string     // what would be accepted if Expected is types.Nop (e.g. "a list")
+}
+
+// checkArguments returns a RuntimeError for the call if the number of arguments or the type of an argument whose
+// parameter isn't types.Nop is wrong. The arguments of types.Nop parameters have to be checked by Call.
+func (b Builtin) checkArguments(call FunctionCall, args []interface{}) *RuntimeError {
+	if len(args) != len(b.Params) {
+		return wrongArgumentCount(call, len(b.Params))
+	}
+	for i, param := range b.Params {
+		if param != types.Nop && !hasBasicType(args[i], param) {
+			return newRuntimeError(call.Arguments[i], fmt.Sprintf("the value %v can't be passed as %v", args[i], param))
+		}
+	}
+	return nil
+}
+
+// hasBasicType checks if the value is of the basic type.
+func hasBasicType(value interface{}, tipe types.Type) bool {
+	switch value.(type) {
+	case bool:
+		return tipe == types.Boolean
+	case int64:
+		return tipe == types.Int
+	case float64:
+		return tipe == types.Float
+	case string:
+		return tipe == types.String
+	}
+	return false
+}
+
+// LookupBuiltin returns the Builtin with the name.
+func LookupBuiltin(name string) (Builtin, bool) {
+	for _, builtin := range builtins {
+		if builtin.Name == name {
+			return builtin, true
+		}
+	}
+	return Builtin{}, false
+}
+
+var builtins = []Builtin{
+	{
+		// println writes the string and a line break to the output
+		Name:    "println",
+		Params:  []types.Type{types.String},
+		Returns: types.Nop,
+		Call: func(interp *Interpreter, call FunctionCall, args []interface{}) interface{} {
+			fmt.Fprintln(interp.out, args[0].(string))
+			return nil
+		},
+	},
+	{
+		// readln reads the next line of the input
+		Name:    "readln",
+		Params:  []types.Type{},
+		Returns: types.String,
+		Call: func(interp *Interpreter, call FunctionCall, args []interface{}) interface{} {
+			return interp.readLine()
+		},
+	},
+	{
+		// len returns the number of elements of a list or map or the number of characters of a string
+		Name:    "len",
+		Params:  []types.Type{types.Nop},
+		Returns: types.Int,
+		Check: func(args []types.Type) (types.Type, *ArgumentError) {
+			switch args[0].(type) {
+			case types.List, types.Map:
+				return types.Int, nil
+			}
+			if args[0] != types.String {
+				return types.Nop, &ArgumentError{Index: 0, Expected: types.Nop, Description: "a list, a map or a String"}
+			}
+			return types.Int, nil
+		},
+		Call: func(interp *Interpreter, call FunctionCall, args []interface{}) interface{} {
+			switch container := args[0].(type) {
+			case *ListValue:
+				return int64(len(container.Elems))
+			case *MapValue:
+				return int64(len(container.Keys))
+			case string:
+				return int64(utf8.RuneCountInString(container))
+			}
+			return wrongArgument(call, 0, args[0], "a list, a map or a String")
+		},
+	},
+	{
+		// append adds the element to the list and returns the same list
+		Name:    "append",
+		Params:  []types.Type{types.Nop, types.Nop},
+		Returns: types.Nop,
+		Check: func(args []types.Type) (types.Type, *ArgumentError) {
+			list, ok := args[0].(types.List)
+			if !ok {
+				return types.Nop, &ArgumentError{Index: 0, Expected: types.Nop, Description: "a list"}
+			}
+			if args[1] != list.Elem {
+				return types.Nop, &ArgumentError{Index: 1, Expected: list.Elem}
+			}
+			return list, nil
+		},
+		Call: func(interp *Interpreter, call FunctionCall, args []interface{}) interface{} {
+			list, ok := args[0].(*ListValue)
+			if !ok {
+				return wrongArgument(call, 0, args[0], "a list")
+			}
+			list.Elems = append(list.Elems, args[1])
+			return list
+		},
+	},
+	{
+		// has checks if the key exists in the map
+		Name:    "has",
+		Params:  []types.Type{types.Nop, types.Nop},
+		Returns: types.Boolean,
+		Check: func(args []types.Type) (types.Type, *ArgumentError) {
+			return types.Boolean, checkMapKey(args)
+		},
+		Call: func(interp *Interpreter, call FunctionCall, args []interface{}) interface{} {
+			m, ok := args[0].(*MapValue)
+			if !ok {
+				return wrongArgument(call, 0, args[0], "a map")
+			}
+			return m.Has(args[1])
+		},
+	},
+	{
+		// delete removes the key from the map
+		Name:    "delete",
+		Params:  []types.Type{types.Nop, types.Nop},
+		Returns: types.Nop,
+		Check: func(args []types.Type) (types.Type, *ArgumentError) {
+			return types.Nop, checkMapKey(args)
+		},
+		Call: func(interp *Interpreter, call FunctionCall, args []interface{}) interface{} {
+			m, ok := args[0].(*MapValue)
+			if !ok {
+				return wrongArgument(call, 0, args[0], "a map")
+			}
+			m.Delete(args[1])
+			return nil
+		},
+	},
+	{
+		// keys returns a list of all keys in the order in which they were inserted
+		Name:    "keys",
+		Params:  []types.Type{types.Nop},
+		Returns: types.Nop,
+		Check: func(args []types.Type) (types.Type, *ArgumentError) {
+			m, ok := args[0].(types.Map)
+			if !ok {
+				return types.Nop, &ArgumentError{Index: 0, Expected: types.Nop, Description: "a map"}
+			}
+			return types.List{Elem: m.Key}, nil
+		},
+		Call: func(interp *Interpreter, call FunctionCall, args []interface{}) interface{} {
+			// the keys are copied so that changing the map doesn't change the list
+			m, ok := args[0].(*MapValue)
+			if !ok {
+				return wrongArgument(call, 0, args[0], "a map")
+			}
+			return &ListValue{Elems: append([]interface{}{}, m.Keys...)}
+		},
+	},
+	{
+		// substr returns the characters of the string from the start index up to the end index
+		Name:    "substr",
+		Params:  []types.Type{types.String, types.Int, types.Int},
+		Returns: types.String,
+		Call: func(interp *Interpreter, call FunctionCall, args []interface{}) interface{} {
+			// the indices count characters like len does
+			runes := []rune(args[0].(string))
+			start, end := args[1].(int64), args[2].(int64)
+			if start < 0 || end < start || end > int64(len(runes)) {
+				return newRuntimeError(call, fmt.Sprintf("substr(%d, %d) is out of range for a string of length %d", start, end, len(runes)))
+			}
+			return string(runes[start:end])
+		},
+	},
+}
+
+// checkMapKey checks the arguments of has and delete, which need a map and a key of the right type.
+func checkMapKey(args []types.Type) *ArgumentError {
+	m, ok := args[0].(types.Map)
+	if !ok {
+		return &ArgumentError{Index: 0, Expected: types.Nop, Description: "a map"}
+	}
+	if args[1] != m.Key {
+		return &ArgumentError{Index: 1, Expected: m.Key}
+	}
+	return nil
+}
+
+// wrongArgument returns the RuntimeError for an argument of a types.Nop parameter which has the wrong type. The
+// description says what would be accepted, like the one of an ArgumentError.
+func wrongArgument(call FunctionCall, index int, value interface{}, description string) *RuntimeError {
+	return newRuntimeError(call.Arguments[index], fmt.Sprintf("the value %v can't be passed where %s is expected", value, description))
+}
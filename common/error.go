@@ -0,0 +1,23 @@
+package common
+
+// RuntimeError stops the execution of a script, e.g. when dividing by zero. It is returned by Eval and passed up through
+// the expressions and blocks like the signals of return, break and continue until it reaches Interpreter.Run.
+type RuntimeError struct {
+	Message string
+	Span    Span // the expression which failed
+}
+
+func (e *RuntimeError) Error() string {
+	return e.Message + " at " + e.Span.Start.String()
+}
+
+// newRuntimeError creates a RuntimeError which points at the expression.
+func newRuntimeError(expr Expr, message string) *RuntimeError {
+	return &RuntimeError{Message: message, Span: expr.Pos()}
+}
+
+// failed checks if the result of Eval is a RuntimeError which has to be passed on.
+func failed(result interface{}) bool {
+	_, ok := result.(*RuntimeError)
+	return ok
+}
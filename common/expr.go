@@ -2,6 +2,7 @@ package common
 
 import (
 	"fmt"
+	"mbs/types"
 	"strings"
 )
 
@@ -9,36 +10,58 @@ import (
 These types are also defining the code execution by implementing the "Expr"-Interface.
 The types of primitive values (string, int, ...) are found in the file "value.go".*/
 
+// Type is the kind of an expression in our AST (e.g. IfType). The types of the values are described by the package
+// types, e.g. the typechecker checks that an Integer has the type types.Int.
 type Type string
 
 // all of the expressions that can occur in our AST
 const (
-	BlockType        Type = "Block"
-	ReadVarType      Type = "ReadVar"
-	WriteVarType     Type = "WriteVar"
-	OperatorType     Type = "Operator"
-	FunctionCallType Type = "FunctionCall"
-	IfType           Type = "If"
-	ForType          Type = "For"
-	NopType          Type = "Nop"
-	BooleanType      Type = "Boolean"
-	IntegerType      Type = "Integer"
-	FloatType        Type = "Float"
-	StringType       Type = "String"
+	BlockType         Type = "Block"
+	ReadVarType       Type = "ReadVar"
+	WriteVarType      Type = "WriteVar"
+	OperatorType      Type = "Operator"
+	UnaryOperatorType Type = "UnaryOperator"
+	FunctionCallType  Type = "FunctionCall"
+	IfType            Type = "If"
+	ForType           Type = "For"
+	FunctionDeclType  Type = "FunctionDecl"
+	ReturnType        Type = "Return"
+	WhileType         Type = "While"
+	BreakType         Type = "Break"
+	ContinueType      Type = "Continue"
+	ListType          Type = "List"
+	IndexType         Type = "Index"
+	WriteIndexType    Type = "WriteIndex"
+	MapType           Type = "Map"
+	StructDeclType    Type = "StructDecl"
+	StructType        Type = "Struct"
+	FieldType         Type = "Field"
+	WriteFieldType    Type = "WriteField"
+	NopType           Type = "Nop"
+	BooleanType       Type = "Boolean"
+	IntegerType       Type = "Integer"
+	FloatType         Type = "Float"
+	StringType        Type = "String"
 )
 
-// stores all variables and their values that can be accessed in the current scope
-var variables map[string]interface{} = make(map[string]interface{})
-
 // the interface that every expression that can occur in our AST implements
 type Expr interface {
 	Print() string
-	Eval() interface{} // used to execute the code the AST represents
-	Type() Type        // the typechecker uses this to easily access the type of an expression
+	Eval(interp *Interpreter) interface{} // used to execute the code the AST represents
+	Type() Type                           // the typechecker uses this to easily access the type of an expression
+	Pos() Span                            // the part of the source code the expression was parsed from
 }
 
 type Block struct {
+	Span
 	Statements []Expr
+	Comments   []Comment // the comments between the statements, nil if there are none
+}
+
+// Comment is a line comment like "// text" or a block comment like "/* text */". Text includes the delimiters.
+type Comment struct {
+	Span
+	Text string
 }
 
 func (b Block) Print() string {
@@ -53,18 +76,18 @@ func (b Block) Print() string {
 	return bld.String()
 }
 
-func (b Block) Eval() interface{} {
-	// saving the variables of the outer scope
-	outerscopeVars := map[string]interface{}{}
-	for k, v := range variables {
-		outerscopeVars[k] = v
-	}
+func (b Block) Eval(interp *Interpreter) interface{} {
+	// the variables defined in the block are "deleted" after exiting it, assignments to outer variables are kept
+	defer interp.enterScope()()
+
 	// executing the code inside the block
 	for _, expr := range b.Statements {
-		expr.Eval()
+		// return, break, continue and errors stop the execution of every block until the function call or loop is reached
+		switch result := expr.Eval(interp); result.(type) {
+		case returnSignal, breakSignal, continueSignal, *RuntimeError:
+			return result
+		}
 	}
-	// restoring the variables after exiting the block to "delete" the variables defined in the scope of the current block
-	variables = outerscopeVars
 	return nil
 }
 
@@ -73,6 +96,7 @@ func (b Block) Type() Type {
 }
 
 type ReadVar struct {
+	Span
 	Name string
 }
 
@@ -80,8 +104,12 @@ func (v ReadVar) Print() string {
 	return v.Name
 }
 
-func (v ReadVar) Eval() interface{} {
-	return variables[v.Name]
+func (v ReadVar) Eval(interp *Interpreter) interface{} {
+	value, ok := interp.variables.lookup(v.Name)
+	if !ok {
+		return newRuntimeError(v, "unknown variable '"+v.Name+"'")
+	}
+	return value
 }
 
 func (v ReadVar) Type() Type {
@@ -89,6 +117,7 @@ func (v ReadVar) Type() Type {
 }
 
 type WriteVar struct {
+	Span
 	Name string
 	Expr Expr
 }
@@ -97,8 +126,12 @@ func (v WriteVar) Print() string {
 	return v.Name + " = " + v.Expr.Print()
 }
 
-func (v WriteVar) Eval() interface{} {
-	variables[v.Name] = v.Expr.Eval()
+func (v WriteVar) Eval(interp *Interpreter) interface{} {
+	value := v.Expr.Eval(interp)
+	if failed(value) {
+		return value
+	}
+	interp.variables.assign(v.Name, value)
 	return nil
 }
 
@@ -106,154 +139,371 @@ func (v WriteVar) Type() Type {
 	return WriteVarType
 }
 
+// OperatorPrecedence lists the binary operators from the lowest to the highest precedence. Operators on the same level
+// are left associative. Longer symbols come first on each level so that ">=" isn't read as ">".
+var OperatorPrecedence = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!="},
+	{">=", "<=", ">", "<"},
+	{"+", "-"},
+	{"*", "/"},
+}
+
+// precedence returns the level of the operator in OperatorPrecedence.
+func precedence(symbol string) int {
+	for level, symbols := range OperatorPrecedence {
+		for _, s := range symbols {
+			if s == symbol {
+				return level
+			}
+		}
+	}
+	return len(OperatorPrecedence)
+}
+
 type Operator struct {
+	Span
 	Symbol    string
 	FirstExp  Expr
 	SecondExp Expr
 }
 
 func (op Operator) Print() string {
-	return op.FirstExp.Print() + " " + op.Symbol + " " + op.SecondExp.Print()
+	// parentheses are only needed if the nested operator binds weaker than this one
+	first, second := op.FirstExp.Print(), op.SecondExp.Print()
+	if nested, ok := op.FirstExp.(Operator); ok && precedence(nested.Symbol) < precedence(op.Symbol) {
+		first = "(" + first + ")"
+	}
+	if nested, ok := op.SecondExp.(Operator); ok && precedence(nested.Symbol) <= precedence(op.Symbol) {
+		second = "(" + second + ")"
+	}
+	return first + " " + op.Symbol + " " + second
 }
-func (op Operator) Eval() interface{} {
+func (op Operator) Eval(interp *Interpreter) interface{} {
 	// getting the primitive value of both expressions
-	firstExp := op.FirstExp.Eval()
-	secondExp := op.SecondExp.Eval()
+	firstExp := op.FirstExp.Eval(interp)
+	if failed(firstExp) {
+		return firstExp
+	}
+	// like in C the second expression of "&&" and "||" is only evaluated if it can change the result
+	if first, ok := firstExp.(bool); ok && (op.Symbol == "&&" && !first || op.Symbol == "||" && first) {
+		return first
+	}
+	secondExp := op.SecondExp.Eval(interp)
+	if failed(secondExp) {
+		return secondExp
+	}
+	firstExp, secondExp = promoteNumbers(firstExp, secondExp)
 
 	// performing the operation
 	switch operator := op.Symbol; operator {
 	case "+":
 		switch firstExp.(type) {
 		case int64:
-			switch secondExp.(type) {
-			case int64:
-				return firstExp.(int64) + secondExp.(int64)
-			case float64:
-				return firstExp.(float64) + secondExp.(float64)
+			if second, ok := secondExp.(int64); ok {
+				return firstExp.(int64) + second
 			}
 		case float64:
-			return firstExp.(float64) + secondExp.(float64)
+			if second, ok := secondExp.(float64); ok {
+				return firstExp.(float64) + second
+			}
 		case string:
-			return firstExp.(string) + secondExp.(string)
+			if second, ok := secondExp.(string); ok {
+				return firstExp.(string) + second
+			}
 		}
 	case "-":
 		switch firstExp.(type) {
 		case int64:
-			switch secondExp.(type) {
-			case int64:
-				return firstExp.(int64) - secondExp.(int64)
-			case float64:
-				return firstExp.(float64) - secondExp.(float64)
+			if second, ok := secondExp.(int64); ok {
+				return firstExp.(int64) - second
 			}
 		case float64:
-			return firstExp.(float64) - secondExp.(float64)
+			if second, ok := secondExp.(float64); ok {
+				return firstExp.(float64) - second
+			}
 		}
 	case "*":
 		switch firstExp.(type) {
 		case int64:
-			switch secondExp.(type) {
-			case int64:
-				return firstExp.(int64) * secondExp.(int64)
-			case float64:
-				return firstExp.(float64) * secondExp.(float64)
+			if second, ok := secondExp.(int64); ok {
+				return firstExp.(int64) * second
 			}
 		case float64:
-			return firstExp.(float64) * secondExp.(float64)
+			if second, ok := secondExp.(float64); ok {
+				return firstExp.(float64) * second
+			}
 		}
 
 	case "/":
 		switch firstExp.(type) {
 		case int64:
-			switch secondExp.(type) {
-			case int64:
-				return firstExp.(int64) / secondExp.(int64)
-			case float64:
-				return firstExp.(float64) / secondExp.(float64)
+			if second, ok := secondExp.(int64); ok {
+				if second == 0 {
+					return newRuntimeError(op, "division by zero")
+				}
+				return firstExp.(int64) / second
 			}
 		case float64:
-			return firstExp.(float64) / secondExp.(float64)
+			if second, ok := secondExp.(float64); ok {
+				return firstExp.(float64) / second
+			}
 		}
 	case "==":
-		return firstExp == secondExp
+		return valuesEqual(firstExp, secondExp)
 	case "!=":
-		return firstExp != secondExp
+		return !valuesEqual(firstExp, secondExp)
 	case ">":
 		switch firstExp.(type) {
 		case int64:
-			switch secondExp.(type) {
-			case int64:
-				return firstExp.(int64) > secondExp.(int64)
-			case float64:
-				return firstExp.(float64) > secondExp.(float64)
+			if second, ok := secondExp.(int64); ok {
+				return firstExp.(int64) > second
 			}
 		case float64:
-			return firstExp.(float64) > secondExp.(float64)
+			if second, ok := secondExp.(float64); ok {
+				return firstExp.(float64) > second
+			}
 		}
 	case "<":
 		switch firstExp.(type) {
 		case int64:
-			switch secondExp.(type) {
-			case int64:
-				return firstExp.(int64) < secondExp.(int64)
-			case float64:
-				return firstExp.(float64) < secondExp.(float64)
+			if second, ok := secondExp.(int64); ok {
+				return firstExp.(int64) < second
 			}
 		case float64:
-			return firstExp.(float64) < secondExp.(float64)
+			if second, ok := secondExp.(float64); ok {
+				return firstExp.(float64) < second
+			}
 		}
 	case ">=":
 		switch firstExp.(type) {
 		case int64:
-			switch secondExp.(type) {
-			case int64:
-				return firstExp.(int64) >= secondExp.(int64)
-			case float64:
-				return firstExp.(float64) >= secondExp.(float64)
+			if second, ok := secondExp.(int64); ok {
+				return firstExp.(int64) >= second
 			}
 		case float64:
-			return firstExp.(float64) >= secondExp.(float64)
+			if second, ok := secondExp.(float64); ok {
+				return firstExp.(float64) >= second
+			}
 		}
 	case "<=":
 		switch firstExp.(type) {
 		case int64:
-			switch secondExp.(type) {
-			case int64:
-				return firstExp.(int64) <= secondExp.(int64)
-			case float64:
-				return firstExp.(float64) <= secondExp.(float64)
+			if second, ok := secondExp.(int64); ok {
+				return firstExp.(int64) <= second
 			}
 		case float64:
-			return firstExp.(float64) <= secondExp.(float64)
+			if second, ok := secondExp.(float64); ok {
+				return firstExp.(float64) <= second
+			}
 		}
 	case "&&":
-		return firstExp.(bool) && secondExp.(bool)
+		if first, ok := firstExp.(bool); ok {
+			if second, ok := secondExp.(bool); ok {
+				return first && second
+			}
+		}
 	case "||":
-		return firstExp.(bool) && secondExp.(bool)
+		if first, ok := firstExp.(bool); ok {
+			if second, ok := secondExp.(bool); ok {
+				return first || second
+			}
+		}
 	}
-	return nil
+	return newRuntimeError(op, fmt.Sprintf("the operator '%s' can't be used with the values %v and %v", op.Symbol, firstExp, secondExp))
 }
 
 func (op Operator) Type() Type {
 	return OperatorType
 }
 
+// promoteNumbers converts an Int to a Float if the other operand is a Float, like the typechecker expects it for
+// arithmetic operators and comparisons.
+func promoteNumbers(first, second interface{}) (interface{}, interface{}) {
+	switch a := first.(type) {
+	case int64:
+		if _, ok := second.(float64); ok {
+			return float64(a), second
+		}
+	case float64:
+		if b, ok := second.(int64); ok {
+			return first, float64(b)
+		}
+	}
+	return first, second
+}
+
+// valuesEqual compares two values. Lists and maps are equal if all of their elements are equal. Values of different
+// types are never equal.
+func valuesEqual(a, b interface{}) bool {
+	return comparison{}.equal(a, b)
+}
+
+// comparison remembers the pairs of values which are already being compared. Lists, maps and structs can contain
+// themselves through their elements, so comparing them again would never end. Such a pair is treated as equal, the rest of the values
+// decides the result.
+type comparison map[[2]interface{}]bool
+
+// visit checks if a and b are the same value or are already being compared, otherwise it remembers the pair.
+func (c comparison) visit(a, b interface{}) bool {
+	pair := [2]interface{}{a, b}
+	if a == b || c[pair] {
+		return true
+	}
+	c[pair] = true
+	return false
+}
+
+func (c comparison) equal(a, b interface{}) bool {
+	switch a := a.(type) {
+	case *ListValue:
+		b, ok := b.(*ListValue)
+		if !ok || len(a.Elems) != len(b.Elems) {
+			return false
+		}
+		if c.visit(a, b) {
+			return true
+		}
+		for i := range a.Elems {
+			if !c.equal(a.Elems[i], b.Elems[i]) {
+				return false
+			}
+		}
+		return true
+	case *MapValue:
+		b, ok := b.(*MapValue)
+		if !ok || len(a.Keys) != len(b.Keys) {
+			return false
+		}
+		if c.visit(a, b) {
+			return true
+		}
+		for _, key := range a.Keys {
+			if !b.Has(key) || !c.equal(a.Get(key), b.Get(key)) {
+				return false
+			}
+		}
+		return true
+	case *StructValue:
+		b, ok := b.(*StructValue)
+		if !ok {
+			return false
+		}
+		if c.visit(a, b) {
+			return true
+		}
+		for name, value := range a.Fields {
+			if !c.equal(value, b.Fields[name]) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+// UnaryOperator applies an operator to a single expression. It's either the logical not ("!") or the numeric
+// negation ("-").
+type UnaryOperator struct {
+	Span
+	Symbol string
+	Exp    Expr
+}
+
+func (op UnaryOperator) Print() string {
+	if _, ok := op.Exp.(Operator); ok {
+		return op.Symbol + "(" + op.Exp.Print() + ")"
+	}
+	return op.Symbol + op.Exp.Print()
+}
+
+func (op UnaryOperator) Eval(interp *Interpreter) interface{} {
+	exp := op.Exp.Eval(interp)
+	if failed(exp) {
+		return exp
+	}
+
+	switch op.Symbol {
+	case "!":
+		if b, ok := exp.(bool); ok {
+			return !b
+		}
+	case "-":
+		switch exp := exp.(type) {
+		case int64:
+			return -exp
+		case float64:
+			return -exp
+		}
+	}
+	return newRuntimeError(op, fmt.Sprintf("the operator '%s' can't be used with the value %v", op.Symbol, exp))
+}
+
+func (op UnaryOperator) Type() Type {
+	return UnaryOperatorType
+}
+
 type FunctionCall struct {
-	Name     string
-	Argument Expr // TODO: we only allow one argument
+	Span
+	Name      string
+	Arguments []Expr
 }
 
 func (f FunctionCall) Print() string {
-	return f.Name + "(" + f.Argument.Print() + ")"
+	args := make([]string, len(f.Arguments))
+	for i, arg := range f.Arguments {
+		args[i] = arg.Print()
+	}
+	return f.Name + "(" + strings.Join(args, ", ") + ")"
 }
 
-func (f FunctionCall) Eval() interface{} {
-	//we only allow the functions println and readln
-	if f.Name == "println" {
-		println(f.Argument.Eval().(string))
-	} else if f.Name == "readln" {
-		var input string
-		fmt.Scanf("%s", &input)
-		return input
+func (f FunctionCall) Eval(interp *Interpreter) interface{} {
+	// the arguments are evaluated in the scope of the caller
+	args := make([]interface{}, len(f.Arguments))
+	for i, arg := range f.Arguments {
+		args[i] = arg.Eval(interp)
+		if failed(args[i]) {
+			return args[i]
+		}
+	}
+
+	// the builtins don't have to be declared by the script
+	if builtin, ok := LookupBuiltin(f.Name); ok {
+		if err := builtin.checkArguments(f, args); err != nil {
+			return err
+		}
+		return builtin.Call(interp, f, args)
+	}
+
+	decl, ok := interp.functions[f.Name]
+	if !ok {
+		return newRuntimeError(f, "unknown function '"+f.Name+"'")
+	}
+	if len(args) != len(decl.Params) {
+		return wrongArgumentCount(f, len(decl.Params))
+	}
+
+	if interp.callDepth == maxCallDepth {
+		return newRuntimeError(f, fmt.Sprintf("the maximum call depth of %d was exceeded", maxCallDepth))
+	}
+
+	// every call gets its own call frame which only contains the parameters
+	callerVars := interp.variables
+	interp.variables = newScope(nil)
+	for i, param := range decl.Params {
+		interp.variables.assign(param.Name, args[i])
+	}
+	interp.callDepth++
+	result := decl.Body.Eval(interp)
+	interp.callDepth--
+	interp.variables = callerVars
+
+	switch result := result.(type) {
+	case returnSignal:
+		return result.Value
+	case *RuntimeError:
+		return result
 	}
 	return nil
 }
@@ -262,18 +512,50 @@ func (f FunctionCall) Type() Type {
 	return FunctionCallType
 }
 
+// wrongArgumentCount returns the RuntimeError for a function call which doesn't have the expected number of arguments.
+func wrongArgumentCount(f FunctionCall, expected int) *RuntimeError {
+	return newRuntimeError(f, fmt.Sprintf("the function '%s' expects %d arguments but got %d", f.Name, expected, len(f.Arguments)))
+}
+
+// If executes the Body if the Condition is true and otherwise the Else block, if there is one. An "else if" is stored as
+// an Else block which only contains another If.
 type If struct {
+	Span
 	Condition Expr
 	Body      Block
+	Else      *Block
 }
 
 func (i If) Print() string {
-	return "if (" + i.Condition.Print() + ") {\n" + i.Body.Print() + "}\n"
+	code := "if (" + i.Condition.Print() + ") {\n" + i.Body.Print() + "}"
+	if i.Else != nil {
+		if elseIf, ok := i.elseIf(); ok {
+			code += " else " + strings.TrimSuffix(elseIf.Print(), "\n")
+		} else {
+			code += " else {\n" + i.Else.Print() + "}"
+		}
+	}
+	return code + "\n"
+}
+
+// elseIf returns the If of an "else if" branch.
+func (i If) elseIf() (If, bool) {
+	if len(i.Else.Statements) != 1 {
+		return If{}, false
+	}
+	elseIf, ok := i.Else.Statements[0].(If)
+	return elseIf, ok
 }
 
-func (i If) Eval() interface{} {
-	if i.Condition.Eval().(bool) {
-		i.Body.Eval()
+func (i If) Eval(interp *Interpreter) interface{} {
+	condition, err := evalCondition(interp, i.Condition)
+	if err != nil {
+		return err
+	}
+	if condition {
+		return i.Body.Eval(interp)
+	} else if i.Else != nil {
+		return i.Else.Eval(interp)
 	}
 	return nil
 }
@@ -282,7 +564,22 @@ func (i If) Type() Type {
 	return IfType
 }
 
+// evalCondition evaluates the condition of an if or a loop. The value has to be a Boolean, otherwise a RuntimeError is
+// returned.
+func evalCondition(interp *Interpreter, condition Expr) (bool, *RuntimeError) {
+	value := condition.Eval(interp)
+	switch value := value.(type) {
+	case bool:
+		return value, nil
+	case *RuntimeError:
+		return false, value
+	}
+	return false, newRuntimeError(condition, fmt.Sprintf("the value %v can't be used as a condition", value))
+}
+
 type For struct {
+	Span
+	Label       string // optional name which can be used by break and continue
 	Init        Expr
 	Condition   Expr
 	Advancement Expr
@@ -290,28 +587,600 @@ type For struct {
 }
 
 func (f For) Print() string {
-	return fmt.Sprintf("for (%s; %s; %s) {\n%s}", f.Init.Print(), f.Condition.Print(), f.Advancement.Print(), f.Body.Print())
+	return fmt.Sprintf("%sfor (%s; %s; %s) {\n%s}", printLabel(f.Label), f.Init.Print(), f.Condition.Print(), f.Advancement.Print(), f.Body.Print())
 }
 
-func (f For) Eval() interface{} {
-	for f.Init.Eval(); f.Condition.Eval().(bool); f.Advancement.Eval() {
-		f.Body.Eval()
+func (f For) Eval(interp *Interpreter) interface{} {
+	// the variable declared in the initialization only exists inside of the loop
+	defer interp.enterScope()()
+
+	if init := f.Init.Eval(interp); failed(init) {
+		return init
+	}
+	for {
+		condition, err := evalCondition(interp, f.Condition)
+		if err != nil {
+			return err
+		}
+		if !condition {
+			return nil
+		}
+
+		if stop, result := loopSignal(f.Label, f.Body.Eval(interp)); stop {
+			return result
+		}
+		if advancement := f.Advancement.Eval(interp); failed(advancement) {
+			return advancement
+		}
 	}
-	return nil
 }
 
 func (f For) Type() Type {
 	return ForType
 }
 
+// Param is a single parameter of a function declaration.
+type Param struct {
+	Span
+	Name string
+	Type types.Type
+}
+
+// FunctionDecl declares a function which can be called by its name. Returns is types.Nop if the function doesn't
+// return a value.
+type FunctionDecl struct {
+	Span
+	Name    string
+	Params  []Param
+	Returns types.Type
+	Body    Block
+}
+
+func (d FunctionDecl) Print() string {
+	return d.signature() + " {\n" + d.Body.Print() + "}\n"
+}
+
+// signature returns the part of the declaration in front of the body like "func f(a Int) Int".
+func (d FunctionDecl) signature() string {
+	params := make([]string, len(d.Params))
+	for i, param := range d.Params {
+		params[i] = param.Name + " " + param.Type.String()
+	}
+	returns := ""
+	if d.Returns != types.Nop {
+		returns = " " + d.Returns.String()
+	}
+	return "func " + d.Name + "(" + strings.Join(params, ", ") + ")" + returns
+}
+
+func (d FunctionDecl) Eval(interp *Interpreter) interface{} {
+	interp.functions[d.Name] = d
+	return nil
+}
+
+func (d FunctionDecl) Type() Type {
+	return FunctionDeclType
+}
+
+// Return leaves the current function call. Expr is Nop if no value is returned.
+type Return struct {
+	Span
+	Expr Expr
+}
+
+func (r Return) Print() string {
+	if r.Expr.Type() == NopType {
+		return "return"
+	}
+	return "return " + r.Expr.Print()
+}
+
+func (r Return) Eval(interp *Interpreter) interface{} {
+	value := r.Expr.Eval(interp)
+	if failed(value) {
+		return value
+	}
+	return returnSignal{Value: value}
+}
+
+func (r Return) Type() Type {
+	return ReturnType
+}
+
+// returnSignal is passed up through the blocks and statements by a return statement until it reaches the function call.
+type returnSignal struct {
+	Value interface{}
+}
+
+type While struct {
+	Span
+	Label     string // optional name which can be used by break and continue
+	Condition Expr
+	Body      Block
+}
+
+func (w While) Print() string {
+	return printLabel(w.Label) + "while (" + w.Condition.Print() + ") {\n" + w.Body.Print() + "}"
+}
+
+func (w While) Eval(interp *Interpreter) interface{} {
+	for {
+		condition, err := evalCondition(interp, w.Condition)
+		if err != nil {
+			return err
+		}
+		if !condition {
+			return nil
+		}
+
+		if stop, result := loopSignal(w.Label, w.Body.Eval(interp)); stop {
+			return result
+		}
+	}
+}
+
+func (w While) Type() Type {
+	return WhileType
+}
+
+// Break leaves the innermost loop or the loop with the given Label.
+type Break struct {
+	Span
+	Label string
+}
+
+func (b Break) Print() string {
+	return strings.TrimSpace("break " + b.Label)
+}
+
+func (b Break) Eval(interp *Interpreter) interface{} {
+	return breakSignal{Label: b.Label}
+}
+
+func (b Break) Type() Type {
+	return BreakType
+}
+
+// Continue skips the rest of the body of the innermost loop or the loop with the given Label.
+type Continue struct {
+	Span
+	Label string
+}
+
+func (c Continue) Print() string {
+	return strings.TrimSpace("continue " + c.Label)
+}
+
+func (c Continue) Eval(interp *Interpreter) interface{} {
+	return continueSignal{Label: c.Label}
+}
+
+func (c Continue) Type() Type {
+	return ContinueType
+}
+
+// breakSignal and continueSignal are passed up through the blocks by break and continue until they reach their loop.
+type breakSignal struct {
+	Label string
+}
+
+type continueSignal struct {
+	Label string
+}
+
+// loopSignal handles the result of executing the body of the loop with the given label. It returns if the loop has to
+// stop and the value which the loop has to pass on to the outer blocks.
+func loopSignal(label string, result interface{}) (bool, interface{}) {
+	switch signal := result.(type) {
+	case breakSignal:
+		if signal.Label == "" || signal.Label == label {
+			return true, nil
+		}
+		return true, signal
+	case continueSignal:
+		if signal.Label == "" || signal.Label == label {
+			return false, nil
+		}
+		return true, signal
+	case returnSignal, *RuntimeError:
+		return true, signal
+	}
+	return false, nil
+}
+
+func printLabel(label string) string {
+	if label == "" {
+		return ""
+	}
+	return label + ": "
+}
+
+// List is a list literal like "[1, 2, 3]". The type of the elements can also be written explicitly like "[]Int{1, 2}"
+// which is needed for empty lists. ElemType is types.Nop if the type wasn't written.
+type List struct {
+	Span
+	ElemType types.Type
+	Elems    []Expr
+}
+
+func (l List) Print() string {
+	elems := make([]string, len(l.Elems))
+	for i, elem := range l.Elems {
+		elems[i] = elem.Print()
+	}
+	if l.ElemType != types.Nop {
+		return types.List{Elem: l.ElemType}.String() + "{" + strings.Join(elems, ", ") + "}"
+	}
+	return "[" + strings.Join(elems, ", ") + "]"
+}
+
+func (l List) Eval(interp *Interpreter) interface{} {
+	list := &ListValue{Elems: make([]interface{}, len(l.Elems))}
+	for i, elem := range l.Elems {
+		list.Elems[i] = elem.Eval(interp)
+		if failed(list.Elems[i]) {
+			return list.Elems[i]
+		}
+	}
+	return list
+}
+
+func (l List) Type() Type {
+	return ListType
+}
+
+// Index reads the element at the position Index of a list like "xs[i]" or the value of the key Index in a map like
+// "m[k]".
+type Index struct {
+	Span
+	Exp   Expr
+	Index Expr
+}
+
+func (i Index) Print() string {
+	return printOperand(i.Exp) + "[" + i.Index.Print() + "]"
+}
+
+func (i Index) Eval(interp *Interpreter) interface{} {
+	container := i.Exp.Eval(interp)
+	if failed(container) {
+		return container
+	}
+	index := i.Index.Eval(interp)
+	if failed(index) {
+		return index
+	}
+
+	switch container := container.(type) {
+	case *ListValue:
+		if err := checkIndex(i, container, index); err != nil {
+			return err
+		}
+		return container.Elems[index.(int64)]
+	case *MapValue:
+		if !container.Has(index) {
+			return newRuntimeError(i, fmt.Sprintf("the key %v doesn't exist in the map", index))
+		}
+		return container.Get(index)
+	}
+	return newRuntimeError(i, fmt.Sprintf("the value %v can't be indexed", container))
+}
+
+func (i Index) Type() Type {
+	return IndexType
+}
+
+// WriteIndex overwrites the element at the position Index of a list like "xs[i] = v" or sets the value of the key
+// Index in a map like "m[k] = v".
+type WriteIndex struct {
+	Span
+	Exp   Expr
+	Index Expr
+	Value Expr
+}
+
+func (w WriteIndex) Print() string {
+	return printOperand(w.Exp) + "[" + w.Index.Print() + "] = " + w.Value.Print()
+}
+
+func (w WriteIndex) Eval(interp *Interpreter) interface{} {
+	container := w.Exp.Eval(interp)
+	if failed(container) {
+		return container
+	}
+	index := w.Index.Eval(interp)
+	if failed(index) {
+		return index
+	}
+	value := w.Value.Eval(interp)
+	if failed(value) {
+		return value
+	}
+
+	switch container := container.(type) {
+	case *ListValue:
+		if err := checkIndex(w, container, index); err != nil {
+			return err
+		}
+		container.Elems[index.(int64)] = value
+	case *MapValue:
+		container.Set(index, value)
+	default:
+		return newRuntimeError(w, fmt.Sprintf("the value %v can't be indexed", container))
+	}
+	return nil
+}
+
+func (w WriteIndex) Type() Type {
+	return WriteIndexType
+}
+
+// checkIndex returns a RuntimeError for the expression if the index isn't an Int or is outside of the list.
+func checkIndex(expr Expr, list *ListValue, index interface{}) *RuntimeError {
+	i, ok := index.(int64)
+	if !ok {
+		return newRuntimeError(expr, fmt.Sprintf("the value %v can't be used as the index of a list", index))
+	}
+	if i < 0 || i >= int64(len(list.Elems)) {
+		return newRuntimeError(expr, fmt.Sprintf("index %d is out of range for a list of length %d", i, len(list.Elems)))
+	}
+	return nil
+}
+
+// printOperand prints an expression which is followed by a postfix like "[i]". Operators need parentheses around them.
+func printOperand(exp Expr) string {
+	switch exp.Type() {
+	case OperatorType, UnaryOperatorType:
+		return "(" + exp.Print() + ")"
+	}
+	return exp.Print()
+}
+
+// ListValue is the value of a list at runtime. Lists are passed by reference, so changing the elements of a list is
+// visible in every variable which holds the same list.
+type ListValue struct {
+	Elems []interface{}
+}
+
+// Map is a map literal like "map[String]Int{"a": 1, "b": 2}". The types of the keys and values always have to be
+// written explicitly.
+type Map struct {
+	Span
+	KeyType   types.Type
+	ValueType types.Type
+	Entries   []MapEntry
+}
+
+type MapEntry struct {
+	Key   Expr
+	Value Expr
+}
+
+func (m Map) Print() string {
+	entries := make([]string, len(m.Entries))
+	for i, entry := range m.Entries {
+		entries[i] = entry.Key.Print() + ": " + entry.Value.Print()
+	}
+	return types.Map{Key: m.KeyType, Value: m.ValueType}.String() + "{" + strings.Join(entries, ", ") + "}"
+}
+
+func (m Map) Eval(interp *Interpreter) interface{} {
+	mapValue := NewMapValue()
+	for _, entry := range m.Entries {
+		key := entry.Key.Eval(interp)
+		if failed(key) {
+			return key
+		}
+		value := entry.Value.Eval(interp)
+		if failed(value) {
+			return value
+		}
+		mapValue.Set(key, value)
+	}
+	return mapValue
+}
+
+func (m Map) Type() Type {
+	return MapType
+}
+
+// MapValue is the value of a map at runtime. Like lists, maps are passed by reference. The keys are kept in the order
+// in which they were inserted, so iterating over a map is deterministic.
+type MapValue struct {
+	Keys   []interface{}
+	values map[interface{}]interface{}
+}
+
+func NewMapValue() *MapValue {
+	return &MapValue{Keys: []interface{}{}, values: make(map[interface{}]interface{})}
+}
+
+func (m *MapValue) Has(key interface{}) bool {
+	_, ok := m.values[key]
+	return ok
+}
+
+func (m *MapValue) Get(key interface{}) interface{} {
+	return m.values[key]
+}
+
+func (m *MapValue) Set(key, value interface{}) {
+	if !m.Has(key) {
+		m.Keys = append(m.Keys, key)
+	}
+	m.values[key] = value
+}
+
+func (m *MapValue) Delete(key interface{}) {
+	if !m.Has(key) {
+		return
+	}
+	delete(m.values, key)
+	for i, k := range m.Keys {
+		if k == key {
+			m.Keys = append(m.Keys[:i], m.Keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// StructDecl declares a new type which has the given fields, like "type Point struct { x Int; y Int }".
+type StructDecl struct {
+	Span
+	Name   string
+	Fields []FieldDecl
+}
+
+type FieldDecl struct {
+	Span
+	Name string
+	Type types.Type
+}
+
+func (d StructDecl) Print() string {
+	bld := strings.Builder{}
+	bld.WriteString("type " + d.Name + " struct {\n")
+	for _, field := range d.Fields {
+		bld.WriteString(field.Name + " " + field.Type.String() + "\n")
+	}
+	bld.WriteString("}\n")
+	return bld.String()
+}
+
+func (d StructDecl) Eval(interp *Interpreter) interface{} {
+	// the declaration is only needed by the typechecker
+	return nil
+}
+
+func (d StructDecl) Type() Type {
+	return StructDeclType
+}
+
+// Struct is a struct literal like "Point{x: 1, y: 2}". Every field has to be given a value.
+type Struct struct {
+	Span
+	Name   string
+	Fields []FieldValue
+}
+
+type FieldValue struct {
+	Span
+	Name  string
+	Value Expr
+}
+
+func (s Struct) Print() string {
+	fields := make([]string, len(s.Fields))
+	for i, field := range s.Fields {
+		fields[i] = field.Name + ": " + field.Value.Print()
+	}
+	return s.Name + "{" + strings.Join(fields, ", ") + "}"
+}
+
+func (s Struct) Eval(interp *Interpreter) interface{} {
+	value := &StructValue{Fields: make(map[string]interface{})}
+	for _, field := range s.Fields {
+		fieldValue := field.Value.Eval(interp)
+		if failed(fieldValue) {
+			return fieldValue
+		}
+		value.Fields[field.Name] = fieldValue
+	}
+	return value
+}
+
+func (s Struct) Type() Type {
+	return StructType
+}
+
+// StructValue is the value of a struct at runtime. Like lists and maps, structs are passed by reference.
+type StructValue struct {
+	Fields map[string]interface{}
+}
+
+// Field reads a field of a struct like "p.x".
+type Field struct {
+	Span
+	Exp  Expr
+	Name string
+}
+
+func (f Field) Print() string {
+	return printOperand(f.Exp) + "." + f.Name
+}
+
+func (f Field) Eval(interp *Interpreter) interface{} {
+	value := f.Exp.Eval(interp)
+	if failed(value) {
+		return value
+	}
+	s, err := structField(f, value, f.Name)
+	if err != nil {
+		return err
+	}
+	return s.Fields[f.Name]
+}
+
+func (f Field) Type() Type {
+	return FieldType
+}
+
+// structField returns the struct which has to contain the field with the name. If the value isn't a struct or
+// doesn't have the field, a RuntimeError for the expression is returned.
+func structField(expr Expr, value interface{}, name string) (*StructValue, *RuntimeError) {
+	s, ok := value.(*StructValue)
+	if !ok {
+		return nil, newRuntimeError(expr, fmt.Sprintf("the value %v doesn't have fields", value))
+	}
+	if _, ok := s.Fields[name]; !ok {
+		return nil, newRuntimeError(expr, "the struct doesn't have the field '"+name+"'")
+	}
+	return s, nil
+}
+
+// WriteField overwrites a field of a struct like "p.x = 3".
+type WriteField struct {
+	Span
+	Exp   Expr
+	Name  string
+	Value Expr
+}
+
+func (w WriteField) Print() string {
+	return printOperand(w.Exp) + "." + w.Name + " = " + w.Value.Print()
+}
+
+func (w WriteField) Eval(interp *Interpreter) interface{} {
+	target := w.Exp.Eval(interp)
+	if failed(target) {
+		return target
+	}
+	value := w.Value.Eval(interp)
+	if failed(value) {
+		return value
+	}
+	s, err := structField(w, target, w.Name)
+	if err != nil {
+		return err
+	}
+	s.Fields[w.Name] = value
+	return nil
+}
+
+func (w WriteField) Type() Type {
+	return WriteFieldType
+}
+
 // Nop is used whenever a statement or expression doesn't do anything e.g. empty values in a for-loop (for (;;)).
-type Nop struct{}
+type Nop struct {
+	Span
+}
 
 func (i Nop) Print() string {
 	return "nop"
 }
 
-func (i Nop) Eval() interface{} {
+func (i Nop) Eval(interp *Interpreter) interface{} {
 	return nil
 }
 
@@ -0,0 +1,49 @@
+package common_test
+
+import "testing"
+
+func TestValuesEqual_cycles(t *testing.T) {
+	// the structs contain themselves through the lists in their fields
+	code := `type P struct { n []P; x Int }
+p = P{n: []P{}, x: 1};
+p.n = append(p.n, p);
+q = P{n: []P{}, x: 1};
+q.n = append(q.n, q);
+r = P{n: []P{}, x: 2};
+r.n = append(r.n, r);
+if (p == q) {
+	println("p == q");
+}
+if (p != r) {
+	println("p != r");
+}
+if (p == p) {
+	println("p == p");
+}`
+	expected := "p == q\np != r\np == p\n"
+	if out := runScript(t, code, ""); out != expected {
+		t.Errorf("got %q wanted %q", out, expected)
+	}
+}
+
+func TestValuesEqual_listCycles(t *testing.T) {
+	// the lists contain themselves through the structs which are their elements
+	code := `type P struct { n []P }
+p = P{n: []P{}};
+p.n = append(p.n, p);
+q = P{n: []P{}};
+q.n = append(q.n, q);
+r = P{n: []P{}};
+r.n = append(r.n, r);
+r.n = append(r.n, r);
+if (p.n == q.n) {
+	println("p.n == q.n");
+}
+if (p.n != r.n) {
+	println("p.n != r.n");
+}`
+	expected := "p.n == q.n\np.n != r.n\n"
+	if out := runScript(t, code, ""); out != expected {
+		t.Errorf("got %q wanted %q", out, expected)
+	}
+}
@@ -0,0 +1,122 @@
+package common
+
+import "strings"
+
+// Format returns the source code of the script in a uniform style: every statement is written on its own line and
+// ends with a semicolon, bodies are indented with tabs and declarations are separated by empty lines. Comments between
+// statements are kept, a comment which is on the same line as the end of a statement stays behind it.
+func Format(block Block) string {
+	f := formatter{}
+	f.statements(block, 0)
+	return f.bld.String()
+}
+
+type formatter struct {
+	bld      strings.Builder
+	comments []Comment // the comments of the current block which weren't written yet
+}
+
+func (f *formatter) statements(block Block, indent int) {
+	outer := f.comments
+	f.comments = block.Comments
+
+	for i, stmt := range block.Statements {
+		if i > 0 && (isDeclaration(stmt) || isDeclaration(block.Statements[i-1])) {
+			f.bld.WriteString("\n")
+		}
+		f.leadingComments(stmt.Pos().Start, indent)
+		f.bld.WriteString(strings.Repeat("\t", indent))
+		f.statement(stmt, indent)
+		f.trailingComments(stmt.Pos().End)
+		f.bld.WriteString("\n")
+	}
+
+	// the comments at the end of the block
+	for _, comment := range f.comments {
+		f.bld.WriteString(strings.Repeat("\t", indent) + comment.Text + "\n")
+	}
+	f.comments = outer
+}
+
+// leadingComments writes the comments in front of the position on their own lines.
+func (f *formatter) leadingComments(pos Position, indent int) {
+	for len(f.comments) > 0 && f.comments[0].Start.Offset < pos.Offset {
+		f.bld.WriteString(strings.Repeat("\t", indent) + f.comments[0].Text + "\n")
+		f.comments = f.comments[1:]
+	}
+}
+
+// trailingComments writes the comments in front of the position and behind it on the same line, so comments inside
+// of an expression aren't lost. Nothing can follow a line comment though.
+func (f *formatter) trailingComments(pos Position) {
+	for len(f.comments) > 0 && (f.comments[0].Start.Offset < pos.Offset || f.comments[0].Start.Line == pos.Line) {
+		comment := f.comments[0]
+		f.bld.WriteString(" " + comment.Text)
+		f.comments = f.comments[1:]
+		if strings.HasPrefix(comment.Text, "//") {
+			return
+		}
+	}
+}
+
+func (f *formatter) statement(stmt Expr, indent int) {
+	switch stmt := stmt.(type) {
+	case If:
+		f.bld.WriteString("if (" + stmt.Condition.Print() + ") ")
+		f.body(stmt.Body, indent)
+		if stmt.Else != nil {
+			f.bld.WriteString(" else ")
+			if elseIf, ok := stmt.elseIf(); ok {
+				f.statement(elseIf, indent)
+			} else {
+				f.body(*stmt.Else, indent)
+			}
+		}
+	case For:
+		f.bld.WriteString(printLabel(stmt.Label) + "for (" + formatOptional(stmt.Init) + "; " + formatOptional(stmt.Condition) +
+			"; " + formatOptional(stmt.Advancement) + ") ")
+		f.body(stmt.Body, indent)
+	case While:
+		f.bld.WriteString(printLabel(stmt.Label) + "while (" + stmt.Condition.Print() + ") ")
+		f.body(stmt.Body, indent)
+	case FunctionDecl:
+		f.bld.WriteString(stmt.signature() + " ")
+		f.body(stmt.Body, indent)
+	case StructDecl:
+		// the comments of the fields belong to the block of the declaration
+		f.bld.WriteString("type " + stmt.Name + " struct {\n")
+		for _, field := range stmt.Fields {
+			f.leadingComments(field.Start, indent+1)
+			f.bld.WriteString(strings.Repeat("\t", indent+1) + field.Name + " " + field.Type.String() + ";")
+			f.trailingComments(field.End)
+			f.bld.WriteString("\n")
+		}
+		f.leadingComments(stmt.End, indent+1)
+		f.bld.WriteString(strings.Repeat("\t", indent) + "}")
+	default:
+		f.bld.WriteString(stmt.Print() + ";")
+	}
+}
+
+// body writes a block in braces, the closing brace is indented like the statement the block belongs to.
+func (f *formatter) body(body Block, indent int) {
+	f.bld.WriteString("{\n")
+	f.statements(body, indent+1)
+	f.bld.WriteString(strings.Repeat("\t", indent) + "}")
+}
+
+// formatOptional formats the parts of a for loop header which can be left out.
+func formatOptional(expr Expr) string {
+	if expr.Type() == NopType {
+		return ""
+	}
+	return expr.Print()
+}
+
+func isDeclaration(stmt Expr) bool {
+	switch stmt.Type() {
+	case FunctionDeclType, StructDeclType:
+		return true
+	}
+	return false
+}
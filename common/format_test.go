@@ -0,0 +1,103 @@
+package common_test
+
+import (
+	. "mbs/common"
+	. "mbs/parser"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	code := `type P struct { x Int; y Float }
+func f(a Int, b P) Float { if (a > 1) { return b.y; } else if (a < 0) { return -1.0; } else { return 2.0 * (1.5 + b.y); } }
+outer: while (true) { for (i=0;i<3;i=i+1) { break outer; } }
+for (;false;) {}
+l = []Int{}; l[0] = -(2+3);`
+	expected := `type P struct {
+	x Int;
+	y Float;
+}
+
+func f(a Int, b P) Float {
+	if (a > 1) {
+		return b.y;
+	} else if (a < 0) {
+		return -1.0;
+	} else {
+		return 2.0 * (1.5 + b.y);
+	}
+}
+
+outer: while (true) {
+	for (i = 0; i < 3; i = i + 1) {
+		break outer;
+	}
+}
+for (; false; ) {
+}
+l = []Int{};
+l[0] = -(2 + 3);
+`
+
+	block, err := ParseCode(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	formatted := Format(*block)
+	if formatted != expected {
+		t.Fatalf("got:\n%s\nwanted:\n%s", formatted, expected)
+	}
+
+	// formatting the formatted code doesn't change it anymore
+	block, err = ParseCode(formatted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again := Format(*block); again != formatted {
+		t.Errorf("got:\n%s\nwanted:\n%s", again, formatted)
+	}
+}
+
+func TestFormat_comments(t *testing.T) {
+	code := `// Point is a position.
+type Point struct { x Int; // horizontal
+y Int; }
+/* the start */ p = Point{x: 1, y: 2};
+while (p.x < 3) { p.x = p.x + 1; // step
+// nothing else
+}`
+	expected := `// Point is a position.
+type Point struct {
+	x Int; // horizontal
+	y Int;
+}
+
+/* the start */
+p = Point{x: 1, y: 2};
+while (p.x < 3) {
+	p.x = p.x + 1; // step
+	// nothing else
+}
+`
+
+	block, err := ParseCode(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if formatted := Format(*block); formatted != expected {
+		t.Errorf("got:\n%s\nwanted:\n%s", formatted, expected)
+	}
+}
+
+func TestFormat_strings(t *testing.T) {
+	// the strings are written the way they were in the code
+	code := "a=`C:\\Pfad`;b=\"\"\"x\n\"y\" \\t\"\"\";c=\"\\u{48}i\nthere\";"
+	expected := "a = `C:\\Pfad`;\nb = \"\"\"x\n\"y\" \\t\"\"\";\nc = \"\\u{48}i\nthere\";\n"
+
+	block, err := ParseCode(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if formatted := Format(*block); formatted != expected {
+		t.Errorf("got:\n%s\nwanted:\n%s", formatted, expected)
+	}
+}
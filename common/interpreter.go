@@ -0,0 +1,57 @@
+package common
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// Interpreter executes scripts. Every Interpreter has its own variables, functions, input and output, so multiple
+// scripts can be executed at the same time.
+type Interpreter struct {
+	variables *scope                  // the variables that can be accessed in the current scope
+	functions map[string]FunctionDecl // the functions that were declared by the script so far
+	callDepth int                     // the number of function calls which haven't returned yet
+	in        *bufio.Reader           // read by the builtin function readln
+	out       io.Writer               // written by the builtin function println
+}
+
+// maxCallDepth is the number of nested function calls at which a script is stopped with a RuntimeError. Without a limit,
+// endless recursion would crash the whole program with a stack overflow.
+const maxCallDepth = 10000
+
+// NewInterpreter creates an Interpreter which reads the input of the script from in and writes its output to out.
+func NewInterpreter(in io.Reader, out io.Writer) *Interpreter {
+	return &Interpreter{
+		variables: newScope(nil),
+		functions: make(map[string]FunctionDecl),
+		in:        bufio.NewReader(in),
+		out:       out,
+	}
+}
+
+// Run executes the script. Variables and functions declared by the top level of the script are kept, so another
+// script which is run by the same Interpreter can use them. The execution stops at the first RuntimeError, which is
+// returned.
+func (interp *Interpreter) Run(block *Block) error {
+	for _, stmt := range block.Statements {
+		if err, ok := stmt.Eval(interp).(*RuntimeError); ok {
+			return err
+		}
+	}
+	return nil
+}
+
+// enterScope creates a new scope inside of the current one and returns the function which leaves it again.
+func (interp *Interpreter) enterScope() func() {
+	outer := interp.variables
+	interp.variables = newScope(outer)
+	return func() { interp.variables = outer }
+}
+
+// readLine reads the next line of the input without the line break. An empty string is returned at the end of the
+// input.
+func (interp *Interpreter) readLine() string {
+	line, _ := interp.in.ReadString('\n')
+	return strings.TrimRight(line, "\r\n")
+}
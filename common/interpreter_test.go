@@ -0,0 +1,187 @@
+package common_test
+
+import (
+	"bytes"
+	. "mbs/common"
+	. "mbs/parser"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// runScript runs the script with the given input and returns its output.
+func runScript(t *testing.T, code, input string) string {
+	block, err := ParseCode(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := bytes.Buffer{}
+	if err := NewInterpreter(strings.NewReader(input), &out).Run(block); err != nil {
+		t.Error(err)
+	}
+	return out.String()
+}
+
+func TestInterpreter_input(t *testing.T) {
+	output := runScript(t, `name = readln();
+greeting = readln();
+println(greeting + ", " + name + "!");
+println("[" + readln() + "]");`, "World\r\nHello\n")
+
+	if output != "Hello, World!\n[]\n" {
+		t.Errorf(`got output "%s"`, output)
+	}
+}
+
+func TestInterpreter_concurrent(t *testing.T) {
+	// every interpreter has its own variables and functions
+	code := `func count(n Int) Int {
+	if (n == 0) {
+		return 0;
+	}
+	return count(n - 1) + 1;
+}
+a = readln();
+for (i = 0; i < 100; i = i + 1) {
+	a = a + ".";
+}
+println(a);`
+
+	wg := sync.WaitGroup{}
+	outputs := make([]string, 10)
+	for i := range outputs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			outputs[i] = runScript(t, code, strconv.Itoa(i))
+		}(i)
+	}
+	wg.Wait()
+
+	for i, output := range outputs {
+		if expected := strconv.Itoa(i) + strings.Repeat(".", 100) + "\n"; output != expected {
+			t.Errorf(`got output "%s" wanted "%s"`, output, expected)
+		}
+	}
+}
+
+func TestInterpreter_runtimeErrors(t *testing.T) {
+	tests := []struct {
+		name, code, expected string
+	}{
+		{"division", "a = 0;\nb = 1 / a;", "division by zero at line 2:5"},
+		{"index", "l = [1, 2];\nprintln(l[2]);", "index 2 is out of range for a list of length 2 at line 2:9"},
+		{"write index", "l = [1];\nl[-1] = 3;", "index -1 is out of range for a list of length 1 at line 2:1"},
+		{"key", `m = map[String]Int{"a": 1};` + "\n" + `x = m["b"];`, "the key b doesn't exist in the map at line 2:5"},
+		{"function", "func f(n Int) Int {\n\treturn 10 / n;\n}\nprintln(\"before\");\nx = f(0);\nprintln(\"after\");", "division by zero at line 2:9"},
+		{"loop", "for (i = 3; i >= 0; i = i - 1) {\n\tx = 6 / i;\n}", "division by zero at line 2:6"},
+		{"recursion", "func f(n Int) Int {\n\treturn f(n + 1);\n}\nx = f(0);\nprintln(\"after\");", "the maximum call depth of 10000 was exceeded at line 2:9"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			block, err := ParseCode(test.code)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			out := bytes.Buffer{}
+			err = NewInterpreter(strings.NewReader(""), &out).Run(block)
+			if err == nil {
+				t.Fatal("expected a runtime error")
+			}
+			if err.Error() != test.expected {
+				t.Errorf(`got error "%s" wanted "%s"`, err.Error(), test.expected)
+			}
+			if strings.Contains(out.String(), "after") {
+				t.Error("the execution wasn't stopped")
+			}
+		})
+	}
+}
+
+// TestInterpreter_untyped runs scripts which wouldn't pass the typechecker, the Interpreter has to stop them with
+// runtime errors instead of panicking.
+func TestInterpreter_untyped(t *testing.T) {
+	tests := []struct {
+		name, code, expected string
+	}{
+		{"if", "if (1) {}", "the value 1 can't be used as a condition at line 1:5"},
+		{"while", "while (\"a\") {}", "the value a can't be used as a condition at line 1:8"},
+		{"for", "for (i = 0; i; i = i + 1) {}", "the value 0 can't be used as a condition at line 1:13"},
+		{"not", "x = !1;", "the operator '!' can't be used with the value 1 at line 1:5"},
+		{"index", "xs = [1];\ny = xs[\"a\"];", "the value a can't be used as the index of a list at line 2:5"},
+		{"write index", "xs = [1];\nxs[true] = 2;", "the value true can't be used as the index of a list at line 2:1"},
+		{"field", "x = 1;\ny = x.a;", "the value 1 doesn't have fields at line 2:5"},
+		{"write field", "x = 1;\nx.a = 2;", "the value 1 doesn't have fields at line 2:1"},
+		{"missing field", "type P struct { a Int }\np = P{a: 1};\ny = p.b;", "the struct doesn't have the field 'b' at line 3:5"},
+		{"builtin", "println(1);", "the value 1 can't be passed as String at line 1:9"},
+		{"builtin arguments", "x = len();", "the function 'len' expects 1 arguments but got 0 at line 1:5"},
+		{"builtin list", "x = append(1, 2);", "the value 1 can't be passed where a list is expected at line 1:12"},
+		{"function arguments", "func f(n Int) Int {\n\treturn n;\n}\nx = f();", "the function 'f' expects 1 arguments but got 0 at line 4:5"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			block, err := ParseCode(test.code)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = NewInterpreter(strings.NewReader(""), &bytes.Buffer{}).Run(block)
+			if err == nil {
+				t.Fatal("expected a runtime error")
+			}
+			if err.Error() != test.expected {
+				t.Errorf(`got error "%s" wanted "%s"`, err.Error(), test.expected)
+			}
+		})
+	}
+}
+
+func TestInterpreter_equalDifferentTypes(t *testing.T) {
+	out := runScript(t, "if ([1] == 1 || map[Int]Int{} == [1]) {\n\tprintln(\"equal\");\n} else {\n\tprintln(\"different\");\n}", "")
+	if out != "different\n" {
+		t.Errorf("got %q wanted %q", out, "different\n")
+	}
+}
+
+func TestInterpreter_shortCircuit(t *testing.T) {
+	code := `func check(name String, result Boolean) Boolean {
+	println(name);
+	return result;
+}
+if (check("a", false) && check("b", true)) {}
+if (check("c", true) || check("d", true)) {}
+if (check("e", true) && check("f", false)) {}
+if (check("g", false) || check("h", true)) {}
+if (false && readln() == "") {}
+println(readln());
+xs = [1, 2];
+i = 2;
+if (i < len(xs) && xs[i] > 0) {
+	println("unreachable");
+}`
+
+	if output := runScript(t, code, "input\n"); output != "a\nc\ne\nf\ng\nh\ninput\n" {
+		t.Errorf(`got output "%s"`, output)
+	}
+}
+
+func TestInterpreter_substr(t *testing.T) {
+	output := runScript(t, `s = "Hällo Welt";
+println(substr(s, 1, 3));
+println(substr(s, 6, len(s)));
+println("[" + substr(s, 4, 4) + "]");`, "")
+
+	if output != "äl\nWelt\n[]\n" {
+		t.Errorf(`got output "%s"`, output)
+	}
+
+	block, _ := ParseCode(`x = substr("abc", 2, 4);`)
+	if err := NewInterpreter(strings.NewReader(""), &bytes.Buffer{}).Run(block); err == nil {
+		t.Error("expected a runtime error")
+	}
+}
@@ -0,0 +1,38 @@
+package common
+
+import "strconv"
+
+// Position is a location in the source code of a script. Lines and columns start at 1, the column is counted in bytes.
+// The offset is the number of bytes before the position.
+type Position struct {
+	File   string
+	Line   int
+	Column int
+	Offset int
+}
+
+// String returns the position like "line 3:5" or "example.mbs:3:5" if the name of the file is known.
+func (p Position) String() string {
+	lineAndColumn := strconv.Itoa(p.Line) + ":" + strconv.Itoa(p.Column)
+	if p.File == "" {
+		return "line " + lineAndColumn
+	}
+	return p.File + ":" + lineAndColumn
+}
+
+// Span is the part of the source code an expression was parsed from. End is the position directly after the
+// expression. Every expression in our AST embeds a Span.
+type Span struct {
+	Start Position
+	End   Position
+}
+
+// Pos returns the Span itself. Since the Span is embedded, this implements the Pos method of the Expr interface.
+func (s Span) Pos() Span {
+	return s
+}
+
+// SetPos changes the Span. The parser uses it to set the span of an expression after the expression was read.
+func (s *Span) SetPos(span Span) {
+	*s = span
+}
@@ -0,0 +1,34 @@
+package common
+
+// scope holds the variables which were declared in a block. Variables of the scopes around it can be read and
+// overwritten too, but variables declared in the scope disappear when the block is left.
+type scope struct {
+	vars  map[string]interface{}
+	outer *scope // nil for the top level of a script and of a function call
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{vars: make(map[string]interface{}), outer: outer}
+}
+
+// lookup returns the value of the variable from the innermost scope which contains it.
+func (s *scope) lookup(name string) (interface{}, bool) {
+	for ; s != nil; s = s.outer {
+		if value, ok := s.vars[name]; ok {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// assign overwrites the variable in the scope where it was declared. If there is no such variable it is declared in
+// this scope.
+func (s *scope) assign(name string, value interface{}) {
+	for current := s; current != nil; current = current.outer {
+		if _, ok := current.vars[name]; ok {
+			current.vars[name] = value
+			return
+		}
+	}
+	s.vars[name] = value
+}
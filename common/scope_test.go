@@ -0,0 +1,75 @@
+package common_test
+
+import (
+	"io"
+	. "mbs/common"
+	. "mbs/parser"
+	"strings"
+	"testing"
+)
+
+// testEvalScript runs the script which has to declare the function "result" and compares what it returns.
+func testEvalScript(t *testing.T, name, code string, expected interface{}) {
+	t.Run(name, func(t *testing.T) {
+		block, err := ParseCode(code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		interp := NewInterpreter(strings.NewReader(""), io.Discard)
+		interp.Run(block)
+
+		if result := (FunctionCall{Name: "result", Arguments: []Expr{}}).Eval(interp); result != expected {
+			t.Errorf(`got %v wanted %v`, result, expected)
+		}
+	})
+}
+
+func TestScopes(t *testing.T) {
+	// assignments to outer variables are kept after the block
+	testEvalScript(t, "for", `func result() Int {
+		a = 0;
+		for (i = 0; i < 3; i = i + 1) {
+			a = a + 1;
+		}
+		return a;
+	}`, int64(3))
+	testEvalScript(t, "while", `func result() Int {
+		a = 0;
+		while (a < 5) {
+			if (true) {
+				a = a + 2;
+			}
+		}
+		return a;
+	}`, int64(6))
+	testEvalScript(t, "else", `func result() String {
+		s = "a";
+		if (false) {
+			s = "b";
+		} else {
+			s = s + "c";
+		}
+		return s;
+	}`, "ac")
+
+	// variables declared inside of the body of a loop are declared again in every iteration
+	testEvalScript(t, "inner", `func result() Int {
+		sum = 0;
+		for (i = 0; i < 3; i = i + 1) {
+			x = i * 10;
+			sum = sum + x;
+		}
+		return sum;
+	}`, int64(30))
+	testEvalScript(t, "recursion", `func fib(n Int) Int {
+		if (n < 2) {
+			return n;
+		}
+		a = fib(n - 1);
+		b = fib(n - 2);
+		return a + b;
+	}
+	func result() Int {
+		return fib(10);
+	}`, int64(55))
+}
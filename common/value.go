@@ -1,10 +1,15 @@
 package common
 
-import "strconv"
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
 
 /*In here are all the primitive data types that our language supports*/
 
 type Boolean struct {
+	Span
 	Data bool
 }
 
@@ -16,7 +21,7 @@ func (b Boolean) Print() string {
 	}
 }
 
-func (b Boolean) Eval() interface{} {
+func (b Boolean) Eval(interp *Interpreter) interface{} {
 	return b.Data
 }
 
@@ -25,11 +30,49 @@ func (b Boolean) Type() Type {
 }
 
 type String struct {
-	Data string
+	Span
+	Data    string
+	Literal string // the literal as it was written in the source code, e.g. a raw string, empty if it wasn't parsed
 }
 
-func (s String) Print() string { return `"` + s.Data + `"` }
-func (s String) Eval() interface{} {
+// Print returns the literal the string was parsed from, so the kind of literal and its escape sequences are kept.
+// Strings which weren't parsed are written as "..." with escape sequences.
+func (s String) Print() string {
+	if s.Literal != "" {
+		return s.Literal
+	}
+	return quoteString(s.Data)
+}
+
+// quoteString returns a string literal which is parsed as the same string again. Line breaks and other characters which
+// aren't printable are written as escape sequences.
+func quoteString(data string) string {
+	bld := strings.Builder{}
+	bld.WriteByte('"')
+	for _, r := range data {
+		switch r {
+		case '\\':
+			bld.WriteString(`\\`)
+		case '"':
+			bld.WriteString(`\"`)
+		case '\n':
+			bld.WriteString(`\n`)
+		case '\t':
+			bld.WriteString(`\t`)
+		case '\r':
+			bld.WriteString(`\r`)
+		default:
+			if unicode.IsPrint(r) {
+				bld.WriteRune(r)
+			} else {
+				bld.WriteString(`\u{` + strconv.FormatInt(int64(r), 16) + `}`)
+			}
+		}
+	}
+	bld.WriteByte('"')
+	return bld.String()
+}
+func (s String) Eval(interp *Interpreter) interface{} {
 	return s.Data
 }
 
@@ -38,11 +81,12 @@ func (s String) Type() Type {
 }
 
 type Integer struct {
+	Span
 	Data int64
 }
 
 func (i Integer) Print() string { return strconv.FormatInt(i.Data, 10) }
-func (i Integer) Eval() interface{} {
+func (i Integer) Eval(interp *Interpreter) interface{} {
 	return i.Data
 }
 func (i Integer) Type() Type {
@@ -50,11 +94,19 @@ func (i Integer) Type() Type {
 }
 
 type Float struct {
+	Span
 	Data float64
 }
 
-func (f Float) Print() string { return strconv.FormatFloat(f.Data, 'f', 5, 64) }
-func (f Float) Eval() interface{} {
+func (f Float) Print() string {
+	// the shortest representation which is read as the same number, it needs a "." so it isn't read as an Int
+	code := strconv.FormatFloat(f.Data, 'f', -1, 64)
+	if !strings.Contains(code, ".") {
+		code += ".0"
+	}
+	return code
+}
+func (f Float) Eval(interp *Interpreter) interface{} {
 	return f.Data
 }
 func (f Float) Type() Type {
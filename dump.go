@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	. "mbs/common"
+	"mbs/types"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var spanType = reflect.TypeOf(Span{})
+
+// dumpAST formats the AST similar to example.parsed. Every node is written as its name followed by its fields in
+// parentheses. Nodes which only contain simple values stay on one line, the fields of every other node are indented
+// on their own lines.
+func dumpAST(expr Expr) string {
+	bld := strings.Builder{}
+	dumpValue(&bld, reflect.ValueOf(expr), 0)
+	return bld.String()
+}
+
+func dumpValue(bld *strings.Builder, value reflect.Value, indent int) {
+	switch value.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if value.IsNil() {
+			bld.WriteString("nil")
+			return
+		}
+		// types are written like in the source code
+		if tipe, ok := value.Interface().(types.Type); ok {
+			bld.WriteString(tipe.String())
+			return
+		}
+		dumpValue(bld, value.Elem(), indent)
+	case reflect.Struct:
+		bld.WriteString(value.Type().Name())
+		bld.WriteString("(")
+		simple := isSimple(value)
+		first := true
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+			// missing comments are left out
+			if field.Type == spanType || value.Field(i).Kind() == reflect.Slice && value.Field(i).IsNil() {
+				continue
+			}
+
+			if simple {
+				if !first {
+					bld.WriteString(", ")
+				}
+			} else {
+				if !first {
+					bld.WriteString(",")
+				}
+				bld.WriteString("\n")
+				bld.WriteString(strings.Repeat("    ", indent+1))
+			}
+			first = false
+
+			bld.WriteString(field.Name)
+			bld.WriteString(": ")
+			dumpValue(bld, value.Field(i), indent+1)
+		}
+		if !simple && !first {
+			bld.WriteString("\n")
+			bld.WriteString(strings.Repeat("    ", indent))
+		}
+		bld.WriteString(")")
+	case reflect.Slice:
+		if value.Len() == 0 {
+			bld.WriteString("[]")
+			return
+		}
+		bld.WriteString("[")
+		for i := 0; i < value.Len(); i++ {
+			bld.WriteString("\n")
+			bld.WriteString(strings.Repeat("    ", indent+1))
+			dumpValue(bld, value.Index(i), indent+1)
+			bld.WriteString(",")
+		}
+		bld.WriteString("\n")
+		bld.WriteString(strings.Repeat("    ", indent))
+		bld.WriteString("]")
+	case reflect.String:
+		bld.WriteString(strconv.Quote(value.String()))
+	default:
+		fmt.Fprint(bld, value.Interface())
+	}
+}
+
+// isSimple checks if the node only has fields which are written on one line.
+func isSimple(value reflect.Value) bool {
+	for i := 0; i < value.NumField(); i++ {
+		switch value.Field(i).Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Slice:
+			if _, ok := value.Field(i).Interface().(types.Type); !ok && !value.Field(i).IsNil() {
+				return false
+			}
+		case reflect.Struct:
+			if value.Field(i).Type() != spanType {
+				return false
+			}
+		}
+	}
+	return true
+}
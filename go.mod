@@ -1,5 +1,5 @@
 module mbs
 
-go 1.15
+go 1.21
 
 require github.com/google/go-cmp v0.5.6
@@ -0,0 +1,255 @@
+// Package lexer splits the source code of a script into tokens which are read by the parser.
+package lexer
+
+import (
+	. "mbs/common"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// lexer reads the tokens of the code one after the other and keeps track of the current position.
+type lexer struct {
+	text     string
+	pos      Position
+	tokens   []Token
+	comments []Comment
+}
+
+// Lex splits the code into tokens. Whitespace is skipped and comments are returned separately. The last token is always
+// EOF. If a part of the code can't be read, an Error token is added instead and the lexer goes on after it, so the
+// parser can report every error. A shebang line like "#!/usr/bin/env mbs" at the start of the script is skipped too.
+func Lex(file, text string) ([]Token, []Comment) {
+	l := lexer{text: text, pos: Position{File: file, Line: 1, Column: 1}}
+	if strings.HasPrefix(text, "#!") {
+		end := strings.IndexByte(text, '\n')
+		if end == -1 {
+			end = len(text)
+		}
+		l.advance(end)
+	}
+
+	for l.next() {
+	}
+	l.tokens = append(l.tokens, Token{Kind: EOF, Span: Span{Start: l.pos, End: l.pos}})
+	return l.tokens, l.comments
+}
+
+// rest returns the code which wasn't read yet.
+func (l *lexer) rest() string {
+	return l.text[l.pos.Offset:]
+}
+
+// advance skips the next n bytes and updates the position.
+func (l *lexer) advance(n int) {
+	for _, c := range []byte(l.text[l.pos.Offset : l.pos.Offset+n]) {
+		if c == '\n' {
+			l.pos.Line++
+			l.pos.Column = 1
+		} else {
+			l.pos.Column++
+		}
+	}
+	l.pos.Offset += n
+}
+
+// emit adds a token for the next n bytes of the code.
+func (l *lexer) emit(kind Kind, text string, n int) {
+	start := l.pos
+	l.advance(n)
+	l.tokens = append(l.tokens, Token{Kind: kind, Text: text, Span: Span{Start: start, End: l.pos}})
+}
+
+// fail adds an Error token for the next n bytes of the code which can't be read. The lexer goes on after them.
+func (l *lexer) fail(message string, n int) bool {
+	l.emit(Error, message, n)
+	return true
+}
+
+// resync returns the length of the text up to the next whitespace or symbol, where the lexer can go on after an error.
+func resync(text string) int {
+	for i, r := range text {
+		if i > 0 && (strings.ContainsRune(" \t\r\n\v\f", r) || isSymbol(text[i:])) {
+			return i
+		}
+	}
+	return len(text)
+}
+
+// isSymbol checks if the text starts with one of the Symbols.
+func isSymbol(text string) bool {
+	for _, symbol := range Symbols {
+		if strings.HasPrefix(text, symbol) {
+			return true
+		}
+	}
+	return false
+}
+
+// next reads the next token or comment. It returns false at the end of the code.
+func (l *lexer) next() bool {
+	rest := strings.TrimLeft(l.rest(), " \t\r\n\v\f")
+	l.advance(len(l.rest()) - len(rest))
+	if rest == "" {
+		return false
+	}
+
+	switch c := rest[0]; {
+	case strings.HasPrefix(rest, "//"):
+		// a line comment ends at the end of the line
+		length := strings.IndexByte(rest, '\n')
+		if length == -1 {
+			length = len(rest)
+		}
+		l.comment(strings.TrimRight(rest[:length], "\r"), length)
+	case strings.HasPrefix(rest, "/*"):
+		end := strings.Index(rest[2:], "*/")
+		if end == -1 {
+			return l.fail("The comment isn't terminated", len(rest))
+		}
+		l.comment(rest[:end+4], end+4)
+	case isLetter(c):
+		length := 1
+		for length < len(rest) && (isLetter(rest[length]) || isDigit(rest[length])) {
+			length++
+		}
+		kind := Name
+		for _, keyword := range Keywords {
+			if rest[:length] == keyword {
+				kind = Keyword
+			}
+		}
+		l.emit(kind, rest[:length], length)
+	case isDigit(c):
+		return l.number(rest)
+	case c == '"' || c == '`':
+		return l.string(rest)
+	default:
+		for _, symbol := range Symbols {
+			if strings.HasPrefix(rest, symbol) {
+				l.emit(Symbol, symbol, len(symbol))
+				return true
+			}
+		}
+		r, _ := utf8.DecodeRuneInString(rest)
+		return l.fail("Unexpected character '"+string(r)+"'", resync(rest))
+	}
+	return true
+}
+
+// comment remembers the comment which is made of the next n bytes.
+func (l *lexer) comment(text string, n int) {
+	start := l.pos
+	l.advance(n)
+	l.comments = append(l.comments, Comment{Span: Span{Start: start, End: l.pos}, Text: text})
+}
+
+// number reads an integer like "123" or a float like "1.5".
+func (l *lexer) number(rest string) bool {
+	length := digits(rest)
+	if length+1 < len(rest) && rest[length] == '.' && isDigit(rest[length+1]) {
+		length += 1 + digits(rest[length+1:])
+		l.emit(FloatLiteral, rest[:length], length)
+		return true
+	}
+	l.emit(IntegerLiteral, rest[:length], length)
+	return true
+}
+
+// string reads a string literal. There are three kinds of string literals:
+//
+//	"text"       which can contain the escape sequences \\, \", \n, \t, \r and \u{hex}
+//	"""text"""   which can contain the same escape sequences and also quotes which aren't escaped
+//	`text`       a raw string which can't contain escape sequences
+//
+// All of them can contain line breaks.
+func (l *lexer) string(rest string) bool {
+	switch {
+	case strings.HasPrefix(rest, `"""`):
+		return l.escapedString(rest, `"""`)
+	case strings.HasPrefix(rest, `"`):
+		return l.escapedString(rest, `"`)
+	}
+
+	end := strings.IndexByte(rest[1:], '`')
+	if end == -1 {
+		return l.fail("The raw string isn't terminated", len(rest))
+	}
+	l.emit(StringLiteral, rest[1:end+1], end+2)
+	return true
+}
+
+// escapedString reads a string which is surrounded by quote and replaces its escape sequences. A string with an invalid
+// escape sequence becomes an Error token.
+func (l *lexer) escapedString(rest, quote string) bool {
+	bld := strings.Builder{}
+	valid := true
+
+	for i := len(quote); i < len(rest); {
+		switch {
+		case strings.HasPrefix(rest[i:], quote):
+			if !valid {
+				return l.fail("Invalid escape sequence in string", i+len(quote))
+			}
+			l.emit(StringLiteral, bld.String(), i+len(quote))
+			return true
+		case rest[i] == '\\':
+			r, length, ok := unescape(rest[i:])
+			if !ok {
+				// the rest of the string is still read so that the lexer goes on after it
+				valid = false
+				i++
+				continue
+			}
+			bld.WriteRune(r)
+			i += length
+		default:
+			bld.WriteByte(rest[i])
+			i++
+		}
+	}
+	return l.fail("The string isn't terminated", len(rest))
+}
+
+var escapes = map[byte]rune{'\\': '\\', '"': '"', 'n': '\n', 't': '\t', 'r': '\r'}
+
+// unescape reads the escape sequence at the start of text. It returns the rune it stands for and its length.
+func unescape(text string) (rune, int, bool) {
+	if len(text) < 2 {
+		return 0, 0, false
+	}
+	if r, ok := escapes[text[1]]; ok {
+		return r, 2, true
+	}
+
+	// unicode code points like "\u{1F600}" have up to 6 hex digits
+	if !strings.HasPrefix(text, "\\u{") {
+		return 0, 0, false
+	}
+	end := strings.IndexByte(text, '}')
+	if end == -1 || end == 3 || end > 9 {
+		return 0, 0, false
+	}
+	codePoint, err := strconv.ParseUint(text[3:end], 16, 32)
+	if err != nil || !utf8.ValidRune(rune(codePoint)) {
+		return 0, 0, false
+	}
+	return rune(codePoint), end + 1, true
+}
+
+// digits returns how many digits the text starts with.
+func digits(text string) int {
+	length := 0
+	for length < len(text) && isDigit(text[length]) {
+		length++
+	}
+	return length
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,108 @@
+package lexer
+
+import (
+	. "mbs/common"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestLex(t *testing.T) {
+	testCase := func(code string, expected ...Token) {
+		t.Run(code, func(t *testing.T) {
+			tokens, _ := Lex("", code)
+			expected = append(expected, Token{Kind: EOF})
+
+			if diff := cmp.Diff(expected, tokens, cmpopts.IgnoreTypes(Span{})); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+
+	testCase("")
+	testCase("  \n\t ")
+	testCase("a1 = 12;",
+		Token{Kind: Name, Text: "a1"}, Token{Kind: Symbol, Text: "="},
+		Token{Kind: IntegerLiteral, Text: "12"}, Token{Kind: Symbol, Text: ";"})
+	testCase("1.5 2. 3",
+		Token{Kind: FloatLiteral, Text: "1.5"}, Token{Kind: IntegerLiteral, Text: "2"},
+		Token{Kind: Symbol, Text: "."}, Token{Kind: IntegerLiteral, Text: "3"})
+	testCase("a>=b>c",
+		Token{Kind: Name, Text: "a"}, Token{Kind: Symbol, Text: ">="}, Token{Kind: Name, Text: "b"},
+		Token{Kind: Symbol, Text: ">"}, Token{Kind: Name, Text: "c"})
+	testCase("if iffy else elsewhere true",
+		Token{Kind: Keyword, Text: "if"}, Token{Kind: Name, Text: "iffy"}, Token{Kind: Keyword, Text: "else"},
+		Token{Kind: Name, Text: "elsewhere"}, Token{Kind: Keyword, Text: "true"})
+	testCase(`"a\tb" `+"`c\\n`",
+		Token{Kind: StringLiteral, Text: "a\tb"}, Token{Kind: StringLiteral, Text: `c\n`})
+	testCase("\"a\nb\" \"\"\"c\n\"d\" e\"\"\"",
+		Token{Kind: StringLiteral, Text: "a\nb"}, Token{Kind: StringLiteral, Text: "c\n\"d\" e"})
+	testCase("a // comment\n/* block */ b",
+		Token{Kind: Name, Text: "a"}, Token{Kind: Name, Text: "b"})
+	testCase("#!/usr/bin/env mbs\na",
+		Token{Kind: Name, Text: "a"})
+}
+
+func TestLex_errors(t *testing.T) {
+	testCase := func(code, message string) {
+		t.Run(code, func(t *testing.T) {
+			tokens, _ := Lex("", code)
+			for _, token := range tokens {
+				if token.Kind == Error {
+					if token.Text != message {
+						t.Errorf("got the error %q wanted %q", token.Text, message)
+					}
+					return
+				}
+			}
+			t.Errorf("got no error wanted %q", message)
+		})
+	}
+
+	testCase("a = #;", "Unexpected character '#'")
+	testCase("a = ä;", "Unexpected character 'ä'")
+	testCase(`"abc`, "The string isn't terminated")
+	testCase(`"\q"`, "Invalid escape sequence in string")
+	testCase("`abc", "The raw string isn't terminated")
+	testCase("/* abc", "The comment isn't terminated")
+}
+
+func TestLex_recovery(t *testing.T) {
+	// the lexer goes on at the next whitespace or symbol after an error
+	tokens, _ := Lex("", "a = #b; \"\\q\" c+äö+d")
+	expected := []Token{
+		{Kind: Name, Text: "a"}, {Kind: Symbol, Text: "="}, {Kind: Error, Text: "Unexpected character '#'"},
+		{Kind: Symbol, Text: ";"}, {Kind: Error, Text: "Invalid escape sequence in string"}, {Kind: Name, Text: "c"},
+		{Kind: Symbol, Text: "+"}, {Kind: Error, Text: "Unexpected character 'ä'"}, {Kind: Symbol, Text: "+"},
+		{Kind: Name, Text: "d"}, {Kind: EOF},
+	}
+	if diff := cmp.Diff(expected, tokens, cmpopts.IgnoreTypes(Span{})); diff != "" {
+		t.Error(diff)
+	}
+	if span := tokens[2].Span; span.Start.Offset != 4 || span.End.Offset != 6 {
+		t.Errorf("got the error at %d-%d wanted 4-6", span.Start.Offset, span.End.Offset)
+	}
+}
+
+func TestLex_positions(t *testing.T) {
+	tokens, comments := Lex("test.mbs", "a = 1;\n  // c\n  bc")
+
+	pos := func(line, column, offset int) Position {
+		return Position{File: "test.mbs", Line: line, Column: column, Offset: offset}
+	}
+	testCase := func(span Span, start, end Position) {
+		if span.Start != start || span.End != end {
+			t.Errorf("got %v-%v wanted %v-%v", span.Start, span.End, start, end)
+		}
+	}
+
+	testCase(tokens[0].Span, pos(1, 1, 0), pos(1, 2, 1))
+	testCase(tokens[2].Span, pos(1, 5, 4), pos(1, 6, 5))
+	testCase(tokens[4].Span, pos(3, 3, 16), pos(3, 5, 18))
+	testCase(tokens[5].Span, pos(3, 5, 18), pos(3, 5, 18))
+	if len(comments) != 1 || comments[0].Text != "// c" {
+		t.Fatalf("got %v wanted one comment", comments)
+	}
+	testCase(comments[0].Span, pos(2, 3, 9), pos(2, 7, 13))
+}
@@ -0,0 +1,72 @@
+package lexer
+
+import (
+	. "mbs/common"
+	"strconv"
+)
+
+// Kind is the kind of a Token.
+type Kind int
+
+const (
+	EOF            Kind = iota // the end of the code
+	Error                      // code which can't be read, Text is the error message
+	Name                       // the name of a variable, function, type, field or label like "abc"
+	Keyword                    // a reserved word like "if"
+	IntegerLiteral             // an integer literal like "123"
+	FloatLiteral               // a float literal like "1.5"
+	StringLiteral              // a string literal, Text is its content with the escape sequences replaced
+	Symbol                     // an operator or a punctuation mark like "==" or "{"
+)
+
+var kindNames = map[Kind]string{
+	EOF:            "end of the code",
+	Error:          "error",
+	Name:           "name",
+	Keyword:        "keyword",
+	IntegerLiteral: "integer",
+	FloatLiteral:   "float",
+	StringLiteral:  "string",
+	Symbol:         "symbol",
+}
+
+func (k Kind) String() string {
+	return kindNames[k]
+}
+
+// Token is a single word, literal or symbol of the code.
+type Token struct {
+	Kind Kind
+	Text string
+	Span Span
+}
+
+// Is checks if the token is the keyword or symbol with the text.
+func (t Token) Is(kind Kind, text string) bool {
+	return t.Kind == kind && t.Text == text
+}
+
+// String describes the token for error messages, e.g. "'{'" or "name 'abc'".
+func (t Token) String() string {
+	switch t.Kind {
+	case Keyword, Symbol:
+		return "'" + t.Text + "'"
+	case Name:
+		return "name '" + t.Text + "'"
+	case StringLiteral:
+		return "string " + strconv.Quote(t.Text)
+	case EOF, Error:
+		return t.Kind.String()
+	}
+	return t.Kind.String() + " " + t.Text
+}
+
+// Keywords are the reserved words of the language which can't be used as names.
+var Keywords = []string{"if", "else", "for", "while", "func", "return", "break", "continue", "type", "struct", "map", "true", "false"}
+
+// Symbols are the operators and punctuation marks of the language. Longer symbols come first so that ">=" isn't read
+// as ">" followed by "=".
+var Symbols = []string{
+	"==", "!=", ">=", "<=", "&&", "||",
+	"+", "-", "*", "/", ">", "<", "!", "=", "(", ")", "{", "}", "[", "]", ",", ";", ":", ".",
+}
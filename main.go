@@ -2,61 +2,118 @@ package main
 
 import (
 	"fmt"
+	"io"
+	. "mbs/common"
 	. "mbs/parser"
 	. "mbs/typechecker"
+	"os"
+	"strings"
 )
 
+// the exit codes of the mbs command
+const (
+	exitOK           = 0
+	exitParseError   = 1
+	exitTypeError    = 2
+	exitRuntimeError = 3
+	exitUsage        = 64
+)
+
+const usage = `Usage: mbs <command> [file]
+
+Commands:
+	run    parses, typechecks and executes the script
+	check  parses and typechecks the script
+	parse  prints the AST of the script
+	fmt    prints the formatted source code of the script
+
+The script is read from stdin if the file is "-" or missing.
+"mbs <file>" is the same as "mbs run <file>", so scripts can start with "#!/usr/bin/env mbs".
+`
+
 func main() {
-	// Enter your program code:
-	code := `
-	a = 123;
-	b = "abc";
-	c = true;
-	d = 4.2;
-	if (c) {
-		println("c is true");
+	os.Exit(runCommand(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// runCommand executes the command line arguments and returns the exit code.
+func runCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 || len(args) > 2 {
+		fmt.Fprint(stderr, usage)
+		return exitUsage
 	}
-	if (a == 123) {
-		println("a is 123");
+
+	command, file := args[0], "-"
+	switch command {
+	case "run", "check", "parse", "fmt":
+		if len(args) == 2 {
+			file = args[1]
+		}
+	default:
+		// "mbs script.mbs" runs the script, this is used by the shebang
+		if len(args) != 1 {
+			fmt.Fprint(stderr, usage)
+			return exitUsage
+		}
+		command, file = "run", args[0]
 	}
-	
-	if (c && true) {
-		println("c && true");
+
+	code, err := readScript(file, stdin)
+	if err != nil {
+		fmt.Fprintln(stderr, "ERROR reading the code:", err)
+		return exitUsage
 	}
-	
-	if (b == "abc") {
-		println("b is abc");
+	if file == "-" {
+		file = "<stdin>"
 	}
-	
-	println(b + "123");
-	for (;false;) {
+
+	block, err := ParseFile(file, code)
+	if err != nil {
+		fmt.Fprintln(stderr, "ERROR parsing the code:")
+		for _, parseErr := range err.(ParseErrors) {
+			fmt.Fprintln(stderr, parseErr.Error())
+		}
+		return exitParseError
 	}
-	
-	for (e = 1; e < 4; e = e + 1) {
-		println("e");
+
+	switch command {
+	case "parse":
+		fmt.Fprintln(stdout, dumpAST(*block))
+		return exitOK
+	case "fmt":
+		// the shebang is skipped by the parser but has to be kept
+		if strings.HasPrefix(code, "#!") {
+			fmt.Fprintln(stdout, strings.SplitN(code, "\n", 2)[0])
+		}
+		fmt.Fprint(stdout, Format(*block))
+		return exitOK
 	}
-	input = readln();
-	println(input);
-	for (e = 0; e < 10; e = e + 2) {
-		println("*");
+
+	if diagnostics := NewChecker().TypeCheckBlock(block); len(diagnostics) != 0 {
+		fmt.Fprintln(stderr, "ERROR typechecking the code:")
+		for _, diagnostic := range diagnostics {
+			fmt.Fprintln(stderr, diagnostic.Error())
+		}
+		return exitTypeError
 	}
-	for (i = 0; i < 10; i = i+1) {
-		a = a+1;
+	if command == "check" {
+		return exitOK
 	}
-	println(input);
-	`
 
-	block, err := ParseCode(code)
+	if err := NewInterpreter(stdin, stdout).Run(block); err != nil {
+		fmt.Fprintln(stderr, "ERROR running the code:", err)
+		return exitRuntimeError
+	}
+	return exitOK
+}
 
-	if err != nil {
-		fmt.Println("ERROR parsing the code!")
+// readScript reads the source code from the file or from stdin if the file is "-".
+func readScript(file string, stdin io.Reader) (string, error) {
+	var code []byte
+	var err error
+	if file == "-" {
+		code, err = io.ReadAll(stdin)
 	} else {
-		valid := TypeCheckBlock(block)
-
-		if !valid {
-			fmt.Println("ERROR typechecking the code")
-		} else {
-			block.Eval() //Code generation/execution
-		}
+		code, err = os.ReadFile(file)
 	}
+	return string(code), err
 }
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunCommand(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "script.mbs")
+	err := os.WriteFile(script, []byte("#!/usr/bin/env mbs\nname = readln();\nprintln(\"Hello \" + name);"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		args   []string
+		stdin  string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{"run", []string{"run", script}, "World", exitOK, "Hello World\n", ""},
+		{"shebang", []string{script}, "World", exitOK, "Hello World\n", ""},
+		{"stdin", []string{"run"}, "println(\"hi\");", exitOK, "hi\n", ""},
+		{"check", []string{"check", script}, "", exitOK, "", ""},
+		{"fmt", []string{"fmt", "-"}, "a=1;if(a>0){println(\"x\");}", exitOK, "a = 1;\nif (a > 0) {\n\tprintln(\"x\");\n}\n", ""},
+		{"parse", []string{"parse"}, "a = 1;", exitOK, "Block(\n    Statements: [\n        WriteVar(\n            Name: \"a\",\n            Expr: Integer(Data: 1)\n        ),\n    ]\n)\n", ""},
+		{"parse error", []string{"run"}, "a = ;", exitParseError, "", "ERROR parsing the code:\n<stdin>:1:5: expected expression, found ';'"},
+		{"parse errors", []string{"check"}, "a = ;\nb = 1;\nc = ;", exitParseError, "", "ERROR parsing the code:\n<stdin>:1:5: expected expression, found ';'\n<stdin>:3:5: expected expression, found ';'\n"},
+		{"type error", []string{"check"}, "a = 1 + \"x\";", exitTypeError, "", "ERROR typechecking the code:"},
+		{"runtime error", []string{"run"}, "a = 0;\nb = 1 / a;", exitRuntimeError, "", "ERROR running the code: division by zero at <stdin>:2:5"},
+		{"missing file", []string{"run", filepath.Join(dir, "missing.mbs")}, "", exitUsage, "", "ERROR reading the code:"},
+		{"no arguments", []string{}, "", exitUsage, "", "Usage:"},
+		{"too many arguments", []string{"a.mbs", "b.mbs"}, "", exitUsage, "", "Usage:"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stdout, stderr := bytes.Buffer{}, bytes.Buffer{}
+			code := runCommand(test.args, strings.NewReader(test.stdin), &stdout, &stderr)
+
+			if code != test.code {
+				t.Errorf("got exit code %d wanted %d, stderr: %s", code, test.code, stderr.String())
+			}
+			if stdout.String() != test.stdout {
+				t.Errorf(`got output "%s" wanted "%s"`, stdout.String(), test.stdout)
+			}
+			if !strings.HasPrefix(stderr.String(), test.stderr) {
+				t.Errorf(`got error "%s" wanted it to start with "%s"`, stderr.String(), test.stderr)
+			}
+		})
+	}
+}
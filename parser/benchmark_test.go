@@ -0,0 +1,93 @@
+package parser
+
+import (
+	"fmt"
+	"mbs/lexer"
+	"strings"
+	"testing"
+)
+
+// benchmarkParseCode parses scripts of different sizes. The time per token stays about the same for every size if the
+// parsing time is linear in the length of the code.
+func benchmarkParseCode(b *testing.B, generate func(n int) string) {
+	for _, n := range []int{100, 400, 1600, 6400} {
+		code := generate(n)
+		tokens, _ := lexer.Lex("", code)
+
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := ParseCode(code); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*len(tokens)), "ns/token")
+		})
+	}
+}
+
+func nested(prefix, open, close, suffix string) func(n int) string {
+	return func(n int) string {
+		return prefix + strings.Repeat(open, n) + "1" + strings.Repeat(close, n) + suffix
+	}
+}
+
+func BenchmarkParseCode_parentheses(b *testing.B) {
+	benchmarkParseCode(b, nested("a = ", "(", ")", ";"))
+}
+
+func BenchmarkParseCode_operators(b *testing.B) {
+	benchmarkParseCode(b, nested("a = ", "(1 + 2 * ", ") - 3", ";"))
+}
+
+func BenchmarkParseCode_unaryOperators(b *testing.B) {
+	benchmarkParseCode(b, nested("a = ", "-(", ")", ";"))
+}
+
+func BenchmarkParseCode_functionCalls(b *testing.B) {
+	benchmarkParseCode(b, nested("", "f(", ")", ";"))
+}
+
+func BenchmarkParseCode_indices(b *testing.B) {
+	benchmarkParseCode(b, nested("a", "[b[", "]]", " = 1;"))
+}
+
+func BenchmarkParseCode_lists(b *testing.B) {
+	benchmarkParseCode(b, nested("a = ", "[", "]", ";"))
+}
+
+func BenchmarkParseCode_blocks(b *testing.B) {
+	benchmarkParseCode(b, func(n int) string {
+		return strings.Repeat("if (a) { while (b) { x = 1; ", n) + strings.Repeat("} }", n)
+	})
+}
+
+func BenchmarkParseCode_comments(b *testing.B) {
+	// every block has comments in front of and behind its nested block, which the outer blocks have to skip
+	benchmarkParseCode(b, func(n int) string {
+		return strings.Repeat("// before\nif (a) {\n", n) + strings.Repeat("}\n/* after */\n", n)
+	})
+}
+
+func BenchmarkParseCode_script(b *testing.B) {
+	benchmarkParseCode(b, func(n int) string {
+		bld := strings.Builder{}
+		bld.WriteString("type Point struct { x Int; y Int }\n")
+		for i := 0; i < n; i++ {
+			fmt.Fprintf(&bld, `func f%d(a Int, p Point) Int {
+	// comment %d
+	xs = []Int{1, 2, 3};
+	m = map[String]Int{"a": 1};
+	for (i = 0; i < len(xs); i = i + 1) {
+		if (xs[i] > a && !(p.x == 0)) {
+			m["b"] = xs[i] * (p.y - 1);
+		} else {
+			p.x = p.x + 1;
+		}
+	}
+	return a + f%d(a - 1, Point{x: 1, y: 2});
+}
+`, i, i, i)
+		}
+		return bld.String()
+	})
+}
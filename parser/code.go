@@ -0,0 +1,139 @@
+package parser
+
+import (
+	"mbs/combinator"
+	. "mbs/common"
+	"mbs/lexer"
+	"sort"
+)
+
+// Code is the input of every parsing function. It is the part of the tokens that is left to be parsed but it also
+// remembers where that part is located in the whole source code, so the parsed expressions can get their positions.
+type Code struct {
+	src   *source
+	index int      // the index of the next token
+	pos   Position // the end of the last token that was parsed or the start of the next token
+}
+
+// source is the whole source code of a script which is shared by every Code created from it.
+type source struct {
+	text     string
+	tokens   []lexer.Token
+	comments []Comment   // all comments, sorted by their position
+	errors   ParseErrors // the syntax errors which were skipped so that parsing could go on
+	state    combinator.State[Code]
+	// claimed marks the comments which were already added to a block. Blocks claim all comments in their span at once,
+	// so claimed[i] is the index after the claimed range which contains comment i, or 0 if the comment is unclaimed.
+	claimed []int
+}
+
+// NewCode creates the input for the parsing functions from the source code of a script. The name of the file is only
+// used in positions and can be empty.
+func NewCode(file, text string) Code {
+	tokens, comments := lexer.Lex(file, text)
+	src := &source{text: text, tokens: tokens, comments: comments, claimed: make([]int, len(comments))}
+	return Code{src: src, pos: Position{File: file, Line: 1, Column: 1}}
+}
+
+// String returns the code that is left to be parsed.
+func (c Code) String() string {
+	return c.src.text[c.pos.Offset:]
+}
+
+// Position returns the position at which the code that is left to be parsed starts.
+func (c Code) Position() Position {
+	return c.pos
+}
+
+// Index returns the index of the next token.
+func (c Code) Index() int {
+	return c.index
+}
+
+// State returns the state of the parser combinators which is shared by the whole source code.
+func (c Code) State() *combinator.State[Code] {
+	return &c.src.state
+}
+
+// Describe describes the next token for error messages.
+func (c Code) Describe() string {
+	return c.peek().String()
+}
+
+// Error creates a ParseError which points at the next token.
+func (c Code) Error(message string) error {
+	return newParseError(c, message)
+}
+
+// peek returns the next token without reading it.
+func (c Code) peek() lexer.Token {
+	return c.src.tokens[c.index]
+}
+
+// next skips the next token. The EOF token is never skipped.
+func (c Code) next() Code {
+	token := c.peek()
+	if token.Kind == lexer.EOF {
+		return c
+	}
+	return Code{src: c.src, index: c.index + 1, pos: token.Span.End}
+}
+
+// stripWhitespace skips all whitespace and comments in front of the next token.
+func (c Code) stripWhitespace() Code {
+	return Code{src: c.src, index: c.index, pos: c.peek().Span.Start}
+}
+
+// claimComments returns the comments between start and end which weren't claimed by a nested block yet, sorted by
+// their position. It returns nil if there are no such comments.
+func claimComments(start, end Code) []Comment {
+	src := start.src
+	from := sort.Search(len(src.comments), func(i int) bool { return src.comments[i].Start.Offset >= start.pos.Offset })
+	to := sort.Search(len(src.comments), func(i int) bool { return src.comments[i].Start.Offset >= end.pos.Offset })
+	if from >= to {
+		return nil
+	}
+
+	// the nested blocks already claimed their ranges, which are skipped, so every comment is only looked at once
+	var comments []Comment
+	for i := from; i < to; {
+		if src.claimed[i] != 0 {
+			i = src.claimed[i]
+			continue
+		}
+		comments = append(comments, src.comments[i])
+		src.claimed[i] = to
+		i++
+	}
+	if src.claimed[from] < to {
+		src.claimed[from] = to
+	}
+	return comments
+}
+
+// reportError remembers a syntax error which was skipped. A part of the code can be parsed more than once if a parser
+// backtracks, so errors at a position which was already reported are ignored.
+func (c Code) reportError(err *ParseError) {
+	for _, reported := range c.src.errors {
+		if reported.Span.Start == err.Span.Start {
+			return
+		}
+	}
+	c.src.errors = append(c.src.errors, err)
+}
+
+// empty checks if there is any code left to be parsed.
+func (c Code) empty() bool {
+	return c.peek().Kind == lexer.EOF
+}
+
+// spanBetween returns the span of the code that was parsed between start and end. Whitespace at the start isn't part
+// of the span.
+func spanBetween(start, end Code) Span {
+	start = start.stripWhitespace()
+	if start.pos.Offset > end.pos.Offset {
+		// nothing but whitespace was parsed
+		start = end
+	}
+	return Span{Start: start.Position(), End: end.Position()}
+}
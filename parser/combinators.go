@@ -1,114 +1,84 @@
 package parser
 
 import (
+	"mbs/combinator"
 	. "mbs/common"
-	"strings"
+	"mbs/lexer"
 )
 
 /*
-	This file contains a number of parsing functions which can be conveniently combined. Less manual error handling is
-	needed if the parser combinator are used because the small building blocks take care of it. Returning values is
-	handled using "out" parameters. This means that the results (e.g. the value read in the name function) are written
-	to the pointer parameter.
+	This file contains the building blocks of the mbs grammar which are combined with the generic parser combinators of
+	the combinator package. Every ParseXxx function has the signature of a combinator.Parser, so the parsing functions
+	can be combined with each other as well.
 */
 
-// Parser is a function type which can be combined using other parser functions.
-type Parser func(string) (string, error)
-
-// name reads a Name (alphanumeric sequence) and writes the result into the adress `out`.
-func name(out *string) Parser {
-	return func(code string) (string, error) {
-		code, name, err := ParseName(code)
-		if err == nil {
-			*out = name
+// token reads a specific symbol like "(" and returns it.
+func token(t string) combinator.Parser[Code, string] {
+	return func(code Code) (Code, string, error) {
+		if !code.peek().Is(lexer.Symbol, t) {
+			return code, "", combinator.Fail(code, "'"+t+"'")
 		}
-		return code, err
-	}
-}
 
-// name reads an Expression using the ParserExpression function and writes the result into the adress `out`.
-func expr(out *Expr) Parser {
-	return func(code string) (string, error) {
-		code, expr, err := ParseExpression(code)
-		if err == nil {
-			*out = expr
-		}
-		return code, err
+		return code.next(), t, nil
 	}
 }
 
-// pfunc parsing function which also returns an Expression into a one compatible with the `Parser` interface.
-func pfunc(out *Expr, fn func(string) (string, Expr, error)) Parser {
-	return func(code string) (string, error) {
-		code, expr, err := fn(code)
-		if err == nil {
-			*out = expr
-		}
-		return code, err
+// tokens returns a parser for each of the symbols.
+func tokens(symbols []string) []combinator.Parser[Code, string] {
+	parsers := make([]combinator.Parser[Code, string], len(symbols))
+	for i, symbol := range symbols {
+		parsers[i] = token(symbol)
 	}
+	return parsers
 }
 
-// name reads a Block using the ParserBlock function and writes the result into the adress `out`.
-func block(out *Block) Parser {
-	return func(code string) (string, error) {
-		code, expr, err := ParseBlock(code)
-		if err == nil {
-			*out = expr
+// keyword reads a reserved word like "if". Names which only start with the keyword like "iffy" aren't matched.
+func keyword(k string) combinator.Parser[Code, string] {
+	return func(code Code) (Code, string, error) {
+		if !code.peek().Is(lexer.Keyword, k) {
+			return code, "", combinator.Fail(code, "'"+k+"'")
 		}
-		return code, err
-	}
-}
-
-// sequence runs the parser in sequnce using the previous parsers code. If any parsers fail the combined parser also fails.
-func sequence(parsers ...Parser) Parser {
-	return func(code string) (string, error) {
-		for _, p := range parsers {
-			var err error
-			code, err = p(code)
 
-			if err != nil {
-				return code, err
-			}
-		}
-		return code, nil
+		return code.next(), k, nil
 	}
 }
 
-// alternative picks runs every parser but stops when one of them worked. If no parser works then an error is returned.
-func alternative(parsers ...Parser) Parser {
-	return func(code string) (string, error) {
-		for _, p := range parsers {
-			tmp, err := p(code)
+// parens reads the value of p surrounded by parentheses like "(expr)".
+func parens[T any](p combinator.Parser[Code, T]) combinator.Parser[Code, T] {
+	return combinator.Between(token("("), p, token(")"))
+}
 
-			if err == nil {
-				return tmp, nil
-			}
-		}
-		return code, &ParseError{Message: "Couldn't match any alternative"}
-	}
+// braces reads the value of p surrounded by braces like "{ statement;... }".
+func braces[T any](p combinator.Parser[Code, T]) combinator.Parser[Code, T] {
+	return combinator.Between(token("{"), p, token("}"))
 }
 
-// token reads a specific sequence of characters but doesn't return the read value.
-func token(t string) Parser {
-	return func(code string) (string, error) {
-		code = stripWhitespaceLeft(code)
-		if !strings.HasPrefix(code, t) {
-			return code, &ParseError{Message: "Couldn't match token '" + t + "'"}
+// loopLabel reads the label of a loop ("name:") and returns the name.
+var loopLabel = combinator.Skip(ParseName, token(":"))
+
+// spanned runs p and sets the span of the value it read, which has to embed a Span, to the code p read.
+func spanned[T any, P interface {
+	*T
+	SetPos(Span)
+}](p combinator.Parser[Code, T]) combinator.Parser[Code, T] {
+	return func(code Code) (Code, T, error) {
+		rest, value, err := p(code)
+		if err != nil {
+			return code, value, err
 		}
 
-		return code[len(t):], nil
+		P(&value).SetPos(spanBetween(code, rest))
+		return rest, value, nil
 	}
 }
 
-// opt runs the provided parser. If the provided parser fails the new parser still succeeds and consumed no input.
-func opt(p Parser) Parser {
-	return func(code string) (string, error) {
-		tmp, err := p(code)
-
+// toExpr converts a parser of a specific kind of expression like If into a parser of Expr.
+func toExpr[T Expr](p combinator.Parser[Code, T]) combinator.Parser[Code, Expr] {
+	return func(code Code) (Code, Expr, error) {
+		rest, value, err := p(code)
 		if err != nil {
-			return code, nil
+			return code, nil, err
 		}
-
-		return tmp, nil
+		return rest, value, nil
 	}
 }
@@ -1,14 +1,39 @@
 package parser
 
-// ParseError is the error type used in parsing functions.
+import (
+	. "mbs/common"
+	"mbs/lexer"
+	"strings"
+)
+
+// ParseError is the error type used in parsing functions. The span points at the code which couldn't be parsed.
 type ParseError struct {
 	Message string
+	Span    Span
 }
 
 func (m *ParseError) Error() string {
-	return m.Message
+	return m.Span.Start.String() + ": " + m.Message
+}
+
+// ParseErrors are all syntax errors of a script sorted by their position. It is returned by ParseFile together with the
+// statements which could be parsed.
+type ParseErrors []*ParseError
+
+func (e ParseErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
 }
 
-func NewParseErrorExpected(expected string) *ParseError {
-	return &ParseError{Message: "Expected '" + expected + "'"}
+// newParseError creates a ParseError which points at the start of the code. If the code couldn't be split into tokens
+// there, the error of the lexer is used instead of the message.
+func newParseError(code Code, message string) *ParseError {
+	if token := code.peek(); token.Kind == lexer.Error {
+		message = token.Text
+	}
+	pos := code.stripWhitespace().Position()
+	return &ParseError{Message: message, Span: Span{Start: pos, End: pos}}
 }
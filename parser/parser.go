@@ -1,264 +1,558 @@
 package parser
 
 import (
+	"errors"
+	"mbs/combinator"
 	. "mbs/common"
-	"regexp"
+	"mbs/lexer"
+	"mbs/types"
+	"sort"
 	"strconv"
-	"strings"
 )
 
-// ParseReadVar reads a single name which represents reading a variable.
-// Example: a
-func ParseReadVar(code string) (string, Expr, error) {
-	code, name, err := ParseName(code)
-	if err != nil {
-		return "", nil, err
+/*
+	The parsers of the grammar are built once when the package is initialized, so the combinators and the closures they
+	create are shared by every call. The exported ParseXxx functions only run them. Rules which contain themselves, like
+	expressions inside of parentheses, refer to each other through these functions and are assigned in init, since a
+	variable can't be initialized with a value which depends on the variable itself.
+*/
+
+var (
+	expression     combinator.Parser[Code, Expr]
+	operandExpr    combinator.Parser[Code, Expr]
+	unaryOperator  combinator.Parser[Code, Expr]
+	typeName       combinator.Parser[Code, types.Type]
+	ifExpr         combinator.Parser[Code, Expr]
+	block          combinator.Parser[Code, Block]
+	statement      combinator.Parser[Code, Expr]
+	topLevelStmt   combinator.Parser[Code, Expr]
+	operatorLevels []combinator.Parser[Code, operand]
+)
+
+func init() {
+	expression = combinator.Memo("expression", func(code Code) (Code, Expr, error) {
+		code, exp, err := operatorLevels[0](code)
+		return code, exp.expr, err
+	})
+	// the statements which assign a value all start with an operand, so it is only parsed once
+	operandExpr = combinator.Memo("operand", parseOperand)
+	unaryOperator = combinator.Memo("unary operator", combinator.Label("expression", combinator.Alt(ParseExpressionWithoutOperator, toExpr(unary))))
+	typeName = combinator.Memo("type", combinator.Label("type", combinator.Alt(listType, mapType, namedType)))
+	ifExpr = toExpr(spanned(combinator.Seq3(
+		combinator.Then(keyword("if"), parens(ParseExpression)),
+		braces(ParseBlock),
+		combinator.Opt(combinator.Then(keyword("else"), combinator.Alt(elseIf, elseBlock)), nil),
+		func(condition Expr, body Block, elseBlock *Block) If {
+			return If{Condition: condition, Body: body, Else: elseBlock}
+		})))
+	block = combinator.Memo("block", func(code Code) (Code, Block, error) {
+		return parseStatements(code, false)
+	})
+
+	// the levels are built from the highest precedence down since every level reads the operands of the next one
+	operatorLevels = make([]combinator.Parser[Code, operand], len(OperatorPrecedence)+1)
+	operatorLevels[len(OperatorPrecedence)] = func(code Code) (Code, operand, error) {
+		rest, exp, err := ParseUnaryOperator(code)
+		return rest, operand{expr: exp, span: spanBetween(code, rest)}, err
+	}
+	for level := len(OperatorPrecedence) - 1; level >= 0; level-- {
+		operatorLevels[level] = combinator.ChainL1(operatorLevels[level+1], operatorLevel(OperatorPrecedence[level]))
 	}
 
-	return code, ReadVar{Name: name}, nil
+	statements := []combinator.Parser[Code, Expr]{
+		combinator.Skip(ParseReturn, token(";")),
+		combinator.Skip(ParseBreak, token(";")),
+		combinator.Skip(ParseContinue, token(";")),
+		combinator.Skip(ParseWriteVar, token(";")),
+		combinator.Skip(ParseWriteIndex, token(";")),
+		combinator.Skip(ParseWriteField, token(";")),
+		combinator.Skip(ParseFunctionCall, token(";")),
+		ParseIf,
+		ParseFor,
+		ParseWhile,
+	}
+	// the different kinds of statements are summarized if none of them got past the first token
+	statement = combinator.Label("statement", combinator.Alt(statements...))
+	topLevelStmt = combinator.Label("statement", combinator.Alt(append(statements, ParseFunctionDecl, ParseStructDecl)...))
+}
+
+var readVar = toExpr(spanned(combinator.Map(ParseName, func(name string) ReadVar {
+	return ReadVar{Name: name}
+})))
+
+// ParseReadVar reads a single name which represents reading a variable.
+// Example: a
+func ParseReadVar(code Code) (Code, Expr, error) {
+	return readVar(code)
 }
 
+var writeVar = toExpr(spanned(combinator.Seq2(combinator.Skip(ParseName, token("=")), ParseExpression, func(name string, value Expr) WriteVar {
+	return WriteVar{Name: name, Expr: value}
+})))
+
 // ParseWriteVar the name of a variable and then the expression which should be written to it on execution.
 // Example: a = 123 + 456
-func ParseWriteVar(code string) (string, Expr, error) {
-	wv := WriteVar{}
-	code, err := sequence(name(&wv.Name), token("="), expr(&wv.Expr))(code)
+func ParseWriteVar(code Code) (Code, Expr, error) {
+	return writeVar(code)
+}
 
+// ParseExpression parses any expression including chains of binary operators like "a + b * c". Operators with a higher
+// precedence bind stronger and operators with the same precedence are left associative, so "1 - 2 * 3 - 4" is read as
+// "(1 - (2 * 3)) - 4".
+func ParseExpression(code Code) (Code, Expr, error) {
+	return expression(code)
+}
+
+// ParseExpressionWithoutOperator tries every possible option that an operand can be. This includes syntax such as
+// literals, function calls or parenthesis around another expresison.
+func ParseExpressionWithoutOperator(code Code) (Code, Expr, error) {
+	return operandExpr(code)
+}
+
+var (
+	operandKinds = combinator.Label("expression", combinator.Alt(
+		ParseParentheses,
+		ParseList,
+		ParseMap,
+		ParseStruct,
+		ParseString,
+		ParseFloat,
+		ParseInteger,
+		ParseFunctionCall,
+		ParseBoolean,
+		ParseReadVar,
+	))
+	index = combinator.Between(token("["), ParseExpression, token("]"))
+	field = combinator.Then(token("."), ParseName)
+)
+
+func parseOperand(code Code) (Code, Expr, error) {
+	start := code
+	code, e, err := operandKinds(code)
 	if err != nil {
 		return code, nil, err
 	}
 
-	return code, wv, err
+	// every operand can be followed by any number of indices and fields like "xs[i].y"
+	for {
+		// the tokens are checked first so that they don't show up in every error message as something that was expected
+		if code.peek().Is(lexer.Symbol, "[") {
+			if tmp, i, err := index(code); err == nil {
+				code = tmp
+				e = Index{Span: spanBetween(start, code), Exp: e, Index: i}
+				continue
+			}
+		} else if code.peek().Is(lexer.Symbol, ".") {
+			if tmp, name, err := field(code); err == nil {
+				code = tmp
+				e = Field{Span: spanBetween(start, code), Exp: e, Name: name}
+				continue
+			}
+		}
+		return code, e, nil
+	}
 }
 
-// ParseExpression tries every possible option that a parser can be. This includes syntax such as literals, function
-// calls or parenthesis around another expresison.
-func ParseExpression(code string) (string, Expr, error) {
-	if remainingCode, exp, err := ParseOperator(code); err == nil {
-		return remainingCode, exp, nil
-	}
-	if remainingCode, exp, err := ParseExpressionWithoutOperator(code); err == nil {
-		return remainingCode, exp, nil
-	}
-	return code, nil, &ParseError{Message: "Couldn't parse any expression"}
+// ParseList parses a list literal like "[1, 2, 3]". The type of the elements can be written explicitly like
+// "[]Int{1, 2, 3}", which is needed for empty lists.
+func ParseList(code Code) (Code, Expr, error) {
+	return list(code)
 }
 
-func ParseExpressionWithoutOperator(code string) (string, Expr, error) {
-	var e Expr = nil
+var (
+	elems     = combinator.SepBy(ParseExpression, token(","))
+	typedList = combinator.Seq2(combinator.Then(combinator.Seq(token("["), token("]")), ParseTypeName), braces(elems), func(elemType types.Type, elems []Expr) List {
+		return List{ElemType: elemType, Elems: elems}
+	})
+	shortList = combinator.Map(combinator.Between(token("["), elems, token("]")), func(elems []Expr) List {
+		return List{ElemType: types.Nop, Elems: elems}
+	})
+	list = toExpr(spanned(combinator.Alt(typedList, shortList)))
+)
 
-	code, err := alternative(
-		pfunc(&e, ParseParentheses),
-		pfunc(&e, ParseString),
-		pfunc(&e, ParseFloat),
-		pfunc(&e, ParseInteger),
-		pfunc(&e, ParseFunctionCall),
-		pfunc(&e, ParseBoolean),
-		pfunc(&e, ParseReadVar),
-	)(code)
+// ParseMap parses a map literal like "map[String]Int{"a": 1, "b": 2}".
+func ParseMap(code Code) (Code, Expr, error) {
+	return mapExpr(code)
+}
 
-	if err != nil {
-		return code, nil, &ParseError{Message: "Couldn't parse any expression"}
-	}
+var (
+	mapEntry = combinator.Seq2(combinator.Skip(ParseExpression, token(":")), ParseExpression, func(key, value Expr) MapEntry {
+		return MapEntry{Key: key, Value: value}
+	})
+	mapExpr = toExpr(spanned(combinator.Seq3(
+		combinator.Then(keyword("map"), combinator.Between(token("["), ParseTypeName, token("]"))),
+		ParseTypeName,
+		braces(combinator.SepBy(mapEntry, token(","))),
+		func(keyType, valueType types.Type, entries []MapEntry) Map {
+			return Map{KeyType: keyType, ValueType: valueType, Entries: entries}
+		})))
+)
 
-	return code, e, nil
+// ParseStruct parses a struct literal like "Point{x: 1, y: 2}".
+func ParseStruct(code Code) (Code, Expr, error) {
+	return structExpr(code)
 }
 
 var (
-	stringRegex       = regexp.MustCompile(`^"((\\"|[^"])*)"`)
-	stringEscapeRegex = regexp.MustCompile(`\\"`)
+	fieldValue = spanned(combinator.Seq2(combinator.Skip(ParseName, token(":")), ParseExpression, func(name string, value Expr) FieldValue {
+		return FieldValue{Name: name, Value: value}
+	}))
+	structExpr = toExpr(spanned(combinator.Seq2(ParseName, braces(combinator.SepBy(fieldValue, token(","))), func(name string, fields []FieldValue) Struct {
+		return Struct{Name: name, Fields: fields}
+	})))
 )
 
-// ParseString parses a string literal surrounded by quotation marks.
-func ParseString(code string) (string, Expr, error) {
-	code = stripWhitespaceLeft(code)
-	match := stringRegex.FindStringIndex(code)
-	if match == nil {
-		return code, nil, &ParseError{Message: "Couldn't parse a string"}
+// ParseWriteIndex parses the assignment of an element of a list like "xs[i] = expr" or of a value in a map like
+// "m[k] = expr".
+func ParseWriteIndex(code Code) (Code, Expr, error) {
+	rest, target, value, err := parseAssignment(code)
+	if err != nil {
+		return code, nil, err
 	}
 
-	data := stringEscapeRegex.ReplaceAllStringFunc(code[match[0]+1:match[1]-1], escapeStringRepl)
+	index, ok := target.(Index)
+	if !ok {
+		return code, nil, newParseError(code, "Expected an element of a list or map")
+	}
 
-	return code[match[1]:], String{Data: data}, nil
+	return rest, WriteIndex{Span: spanBetween(code, rest), Exp: index.Exp, Index: index.Index, Value: value}, nil
 }
 
-func escapeStringRepl(match string) string {
-	switch match[1] {
-	case 'r':
-		return "\r"
-	case 'n':
-		return "\n"
-	case 't':
-		return "\t"
-	default:
-		return match[1:2]
+// ParseWriteField parses the assignment of a field of a struct like "p.x = expr".
+func ParseWriteField(code Code) (Code, Expr, error) {
+	rest, target, value, err := parseAssignment(code)
+	if err != nil {
+		return code, nil, err
+	}
+
+	field, ok := target.(Field)
+	if !ok {
+		return code, nil, newParseError(code, "Expected a field of a struct")
 	}
+
+	return rest, WriteField{Span: spanBetween(code, rest), Exp: field.Exp, Name: field.Name, Value: value}, nil
 }
 
-// ParseBoolean parses a boolean literal. It can be either "true" or "false".
-func ParseBoolean(code string) (string, Expr, error) {
-	code = stripWhitespaceLeft(code)
-	if strings.HasPrefix(code, "true") {
-		return code[4:], Boolean{Data: true}, nil
-	} else if strings.HasPrefix(code, "false") {
-		return code[5:], Boolean{Data: false}, nil
+var assignment = combinator.Seq(combinator.Skip(ParseExpressionWithoutOperator, token("=")), ParseExpression)
+
+// parseAssignment parses the target and the value of an assignment like "xs[i].y = expr".
+func parseAssignment(code Code) (Code, Expr, Expr, error) {
+	rest, values, err := assignment(code)
+	if err != nil {
+		return code, nil, nil, err
 	}
-	return code, nil, &ParseError{Message: "Couldn't parse the expression to a Boolean"}
+
+	return rest, values[0], values[1], nil
 }
 
-var (
-	intRegex = regexp.MustCompile(`^-?\d+`)
-)
+// ParseString parses a string literal. The escape sequences were already replaced by the lexer.
+func ParseString(code Code) (Code, Expr, error) {
+	token := code.peek()
+	if token.Kind != lexer.StringLiteral {
+		return code, nil, combinator.Fail(code, "string")
+	}
+
+	rest := code.next()
+	literal := code.src.text[token.Span.Start.Offset:token.Span.End.Offset]
+	return rest, String{Span: spanBetween(code, rest), Data: token.Text, Literal: literal}, nil
+}
+
+// ParseBoolean parses a boolean literal. It can be either "true" or "false".
+func ParseBoolean(code Code) (Code, Expr, error) {
+	token := code.peek()
+	if token.Is(lexer.Keyword, "true") || token.Is(lexer.Keyword, "false") {
+		rest := code.next()
+		return rest, Boolean{Span: spanBetween(code, rest), Data: token.Text == "true"}, nil
+	}
+	return code, nil, combinator.Fail(code, "boolean")
+}
 
 // ParseInteger parses an integer of type "int". Can be negative.
-func ParseInteger(code string) (string, Expr, error) {
-	code = stripWhitespaceLeft(code)
-	match := intRegex.FindString(code)
-	if integer, err := strconv.ParseInt(match, 10, 64); err == nil {
-		return code[len(match):], Integer{Data: integer}, nil
+func ParseInteger(code Code) (Code, Expr, error) {
+	rest, number, ok := parseNumber(code, lexer.IntegerLiteral)
+	if integer, err := strconv.ParseInt(number, 10, 64); ok && err == nil {
+		return rest, Integer{Span: spanBetween(code, rest), Data: integer}, nil
 	}
 
-	return code, nil, &ParseError{Message: "Couldn't parse the expression to an Integer"}
+	return code, nil, combinator.Fail(code, "integer")
 }
 
-var (
-	floatRegex = regexp.MustCompile(`^-?\d+\.\d+`)
-)
-
 // ParseFloat parses a floating point number of type "double" of the format "x.y" or "-x.y".
-func ParseFloat(code string) (string, Expr, error) {
-	code = stripWhitespaceLeft(code)
-	match := floatRegex.FindString(code)
-	if float, err := strconv.ParseFloat(match, 64); err == nil {
-		return code[len(match):], Float{Data: float}, nil
+func ParseFloat(code Code) (Code, Expr, error) {
+	rest, number, ok := parseNumber(code, lexer.FloatLiteral)
+	if float, err := strconv.ParseFloat(number, 64); ok && err == nil {
+		return rest, Float{Span: spanBetween(code, rest), Data: float}, nil
 	}
 
-	return code, nil, &ParseError{Message: "Couldn't parse the expression to a Float"}
+	return code, nil, combinator.Fail(code, "float")
 }
 
-// ParseFunctionCall parses a function call in the form of `name(expr)` or `name()`.
-func ParseFunctionCall(code string) (string, Expr, error) {
-	fn := FunctionCall{Argument: Nop{}}
-	code, err := sequence(name(&fn.Name), token("("), opt(expr(&fn.Argument)), token(")"))(code)
+// parseNumber reads a number token of the given kind. A "-" directly in front of the number is part of the literal,
+// "-1" is a negative number but "- 1" is the negation of a number.
+func parseNumber(code Code, kind lexer.Kind) (Code, string, bool) {
+	sign := ""
+	if minus := code.peek(); minus.Is(lexer.Symbol, "-") && code.next().peek().Span.Start.Offset == minus.Span.End.Offset {
+		sign = "-"
+		code = code.next()
+	}
+
+	token := code.peek()
+	if token.Kind != kind {
+		return code, "", false
+	}
+	return code.next(), sign + token.Text, true
+}
 
-	return code, fn, err
+// ParseFunctionCall parses a function call in the form of `name(expr, ...)` or `name()`.
+func ParseFunctionCall(code Code) (Code, Expr, error) {
+	return functionCall(code)
 }
 
-// ParseParentheses parses an expression surrounded by parentheses.
-func ParseParentheses(code string) (string, Expr, error) {
-	var res Expr = nil
-	code, err := sequence(token("("), expr(&res), token(")"))(code)
+var functionCall = toExpr(spanned(combinator.Seq2(ParseName, parens(combinator.SepBy(ParseExpression, token(","))), func(name string, args []Expr) FunctionCall {
+	return FunctionCall{Name: name, Arguments: args}
+})))
 
-	return code, res, err
+// ParseParentheses parses an expression surrounded by parentheses.
+func ParseParentheses(code Code) (Code, Expr, error) {
+	return parentheses(code)
 }
 
+var parentheses = parens(ParseExpression)
+
 var (
-	operators = []string{"+", "-", "*", "/", ">", "<", "==", "!=", ">=", "<=", "&&", "||"}
+	unaryOperators = []string{"!", "-"}
 )
 
-// ParseOpterator parses two expressions with an operator inbetween them.
-func ParseOperator(code string) (string, Expr, error) {
-	code = stripWhitespaceLeft(code)
-	code, firstExp, err := ParseExpressionWithoutOperator(code)
-	if err != nil {
-		return code, nil, &ParseError{Message: "Couldn't parse first expression!"}
-	}
+// ParseUnaryOperator parses an operand which can have any number of unary operators in front of it like "!done" or
+// "-x". Unary operators bind stronger than every binary operator. Negative number literals like "-1" are still read as
+// literals.
+func ParseUnaryOperator(code Code) (Code, Expr, error) {
+	return unaryOperator(code)
+}
 
-	code = stripWhitespaceLeft(code)
-	operator := ""
-	for _, op := range operators {
-		if strings.HasPrefix(code, op) {
-			operator = op
-			break
+var unary = spanned(combinator.Seq2(combinator.Alt(tokens(unaryOperators)...), ParseUnaryOperator, func(symbol string, exp Expr) UnaryOperator {
+	return UnaryOperator{Symbol: symbol, Exp: exp}
+}))
+
+// operand is an expression together with the span of the code it was read from. The span can differ from the span of
+// the expression if the expression was surrounded by parentheses.
+type operand struct {
+	expr Expr
+	span Span
+}
+
+// operatorLevel returns a parser for one of the operators of a precedence level. It returns the function which combines
+// the operands around the operator. operatorLevels chains the levels together, so every level reads the operands of
+// the next higher level.
+func operatorLevel(symbols []string) combinator.Parser[Code, func(operand, operand) operand] {
+	return combinator.Map(combinator.Label("operator", combinator.Alt(tokens(symbols)...)), func(symbol string) func(operand, operand) operand {
+		return func(first, second operand) operand {
+			span := Span{Start: first.span.Start, End: second.span.End}
+			return operand{expr: Operator{Span: span, Symbol: symbol, FirstExp: first.expr, SecondExp: second.expr}, span: span}
 		}
-	}
-	if operator == "" {
-		return code, nil, &ParseError{Message: "Couldn't parse the expression to an Operator"}
-	}
+	})
+}
 
-	code, secondExp, err := ParseExpressionWithoutOperator(stripWhitespaceLeft(code[len(operator):]))
+// ParseTypeName parses the name of a type like "Int", "[]String", "map[String]Int" or "Point".
+func ParseTypeName(code Code) (Code, types.Type, error) {
+	rest, tipe, err := typeName(code)
 	if err != nil {
-		return code, nil, &ParseError{Message: "Couldn't parse second expression!"}
+		return code, types.Nop, err
 	}
-
-	return code, Operator{Symbol: operator, FirstExp: firstExp, SecondExp: secondExp}, nil
+	return rest, tipe, nil
 }
 
 var (
-	nameRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9]*`)
+	listType = combinator.Map(combinator.Then(combinator.Seq(token("["), token("]")), ParseTypeName), func(elem types.Type) types.Type {
+		return types.List{Elem: elem}
+	})
+	mapType = combinator.Seq2(
+		combinator.Then(keyword("map"), combinator.Between(token("["), ParseTypeName, token("]"))),
+		ParseTypeName,
+		func(key, value types.Type) types.Type {
+			return types.Map{Key: key, Value: value}
+		})
+	namedType = combinator.Map(ParseName, func(name string) types.Type {
+		// every name which isn't a built in type is the name of a struct
+		if tipe, ok := types.Lookup(name); ok {
+			return tipe
+		}
+		return types.Struct{Name: name}
+	})
 )
 
 // ParseName takes an input and returns one of:
 // - (the code without the name, the name, nil)
-// - (nil, nil, the error)
-func ParseName(code string) (string, string, error) {
-	codeWithoutWhitespace := stripWhitespaceLeft(code)
-	name := nameRegex.FindString(codeWithoutWhitespace)
-
-	if name == "" {
-		return "", "", &ParseError{Message: "Couldn't parse the name"}
+// - (the code, "", the error)
+func ParseName(code Code) (Code, string, error) {
+	token := code.peek()
+	if token.Kind != lexer.Name {
+		return code, "", combinator.Fail(code, "name")
 	}
 
-	return codeWithoutWhitespace[len(name):], name, nil
+	return code.next(), token.Text, nil
+}
+
+// ParseIf parses an if condition like "if (expr) { statement;... }" which can be followed by "else if (expr) {...}"
+// branches and a final "else {...}" branch.
+func ParseIf(code Code) (Code, Expr, error) {
+	return ifExpr(code)
 }
 
-// ParseIf parses an if condition like "if (expr) { statement;... }"
-func ParseIf(code string) (string, Expr, error) {
-	if_ := If{}
-	code, err := sequence(token("if"), token("("), expr(&if_.Condition), token(")"), token("{"), block(&if_.Body), token("}"))(code)
+var (
+	elseIf = combinator.Map(ParseIf, func(elseIf Expr) *Block {
+		return &Block{Span: elseIf.Pos(), Statements: []Expr{elseIf}}
+	})
+	elseBlock = combinator.Map(braces(ParseBlock), func(block Block) *Block {
+		return &block
+	})
+)
 
-	if err != nil {
-		return code, nil, err
-	}
+// ParseFor parses a for loop like "for (a = expr; condition; b = expr) { statement;... }". The loop can have a label
+// like "outer: for (...) {...}".
+func ParseFor(code Code) (Code, Expr, error) {
+	return forExpr(code)
+}
+
+var (
+	forHeader = combinator.Seq3(
+		combinator.Skip(combinator.Opt(ParseWriteVar, Expr(&Nop{})), token(";")),
+		combinator.Skip(ParseExpression, token(";")),
+		combinator.Opt(ParseWriteVar, Expr(&Nop{})),
+		func(init, condition, advancement Expr) For {
+			return For{Init: init, Condition: condition, Advancement: advancement}
+		})
+	forExpr = toExpr(spanned(combinator.Seq3(
+		combinator.Opt(loopLabel, ""),
+		combinator.Then(keyword("for"), parens(forHeader)),
+		braces(ParseBlock),
+		func(label string, for_ For, body Block) For {
+			for_.Label, for_.Body = label, body
+			return for_
+		})))
+)
 
-	return code, if_, nil
+// ParseWhile parses a while loop like "while (condition) { statement;... }". The loop can have a label like
+// "outer: while (...) {...}".
+func ParseWhile(code Code) (Code, Expr, error) {
+	return while(code)
 }
 
-// ParseIf parses a for loop like "for (a = expr; condition; b = expr) { statement;... }"
-func ParseFor(code string) (string, Expr, error) {
-	for_ := For{Init: &Nop{}, Condition: &Nop{}, Advancement: &Nop{}}
+var while = toExpr(spanned(combinator.Seq3(
+	combinator.Opt(loopLabel, ""),
+	combinator.Then(keyword("while"), parens(ParseExpression)),
+	braces(ParseBlock),
+	func(label string, condition Expr, body Block) While {
+		return While{Label: label, Condition: condition, Body: body}
+	})))
+
+// ParseBreak parses a break statement like "break" or "break label".
+func ParseBreak(code Code) (Code, Expr, error) {
+	return breakExpr(code)
+}
 
-	code, err := sequence(
-		token("for"),
-		token("("),
-		opt(pfunc(&for_.Init, ParseWriteVar)),
-		token(";"),
-		expr(&for_.Condition),
-		token(";"),
-		opt(pfunc(&for_.Advancement, ParseWriteVar)),
-		token(")"),
-		token("{"),
-		block(&for_.Body),
-		token("}"))(code)
+var breakExpr = toExpr(spanned(combinator.Map(combinator.Then(keyword("break"), combinator.Opt(ParseName, "")), func(label string) Break {
+	return Break{Label: label}
+})))
 
-	if err != nil {
-		return code, nil, err
-	}
+// ParseContinue parses a continue statement like "continue" or "continue label".
+func ParseContinue(code Code) (Code, Expr, error) {
+	return continueExpr(code)
+}
+
+var continueExpr = toExpr(spanned(combinator.Map(combinator.Then(keyword("continue"), combinator.Opt(ParseName, "")), func(label string) Continue {
+	return Continue{Label: label}
+})))
 
-	return code, for_, nil
+// ParseFunctionDecl parses a function declaration like "func name(a Int, b String) Int { statement;... }". The return
+// type can be left out if the function doesn't return a value.
+func ParseFunctionDecl(code Code) (Code, Expr, error) {
+	return functionDecl(code)
 }
 
+var (
+	param = spanned(combinator.Seq2(ParseName, ParseTypeName, func(name string, tipe types.Type) Param {
+		return Param{Name: name, Type: tipe}
+	}))
+	functionDecl = toExpr(spanned(combinator.Seq4(
+		combinator.Then(keyword("func"), ParseName),
+		parens(combinator.SepBy(param, token(","))),
+		combinator.Opt(ParseTypeName, types.Type(types.Nop)),
+		braces(ParseBlock),
+		func(name string, params []Param, returns types.Type, body Block) FunctionDecl {
+			return FunctionDecl{Name: name, Params: params, Returns: returns, Body: body}
+		})))
+)
+
+// ParseStructDecl parses the declaration of a struct type like "type Point struct { x Int; y Int }".
+func ParseStructDecl(code Code) (Code, Expr, error) {
+	return structDecl(code)
+}
+
+var (
+	fieldDecl = spanned(combinator.Seq2(ParseName, ParseTypeName, func(name string, tipe types.Type) FieldDecl {
+		return FieldDecl{Name: name, Type: tipe}
+	}))
+	structDecl = toExpr(spanned(combinator.Seq2(
+		combinator.Then(keyword("type"), combinator.Skip(ParseName, keyword("struct"))),
+		braces(combinator.Skip(combinator.SepBy(fieldDecl, token(";")), combinator.Opt(token(";"), ""))),
+		func(name string, fields []FieldDecl) StructDecl {
+			return StructDecl{Name: name, Fields: fields}
+		})))
+)
+
+// ParseReturn parses a return statement like "return expr" or "return".
+func ParseReturn(code Code) (Code, Expr, error) {
+	return returnExpr(code)
+}
+
+var returnExpr = toExpr(spanned(combinator.Map(combinator.Then(keyword("return"), combinator.Opt(ParseExpression, Expr(Nop{}))), func(exp Expr) Return {
+	return Return{Expr: exp}
+})))
+
 // ParseBlock parses a list of statement. It's used in the ParseIf and ParseFor functions.
-func ParseBlock(code string) (string, Block, error) {
+func ParseBlock(code Code) (Code, Block, error) {
+	return block(code)
+}
+
+// parseStatements parses a list of statements. Declarations of functions and structs are only allowed on the top level
+// of a script.
+func parseStatements(code Code, topLevel bool) (Code, Block, error) {
 	// Either:
+	// - Return
+	// - Break
+	// - Continue
 	// - WriteVar
+	// - WriteIndex
+	// - WriteField
 	// - FunctionCall
 	// - If
 	// - For
+	// - While
+	// - FunctionDecl (only on the top level)
+	// - StructDecl (only on the top level)
 
+	start := code
 	stmts := make([]Expr, 0)
 
-	for {
-		var e Expr = nil
+	stmt := statement
+	if topLevel {
+		stmt = topLevelStmt
+	}
 
-		tmp, err := alternative(
-			sequence(pfunc(&e, ParseWriteVar), token(";")),
-			sequence(pfunc(&e, ParseFunctionCall), token(";")),
-			pfunc(&e, ParseIf),
-			pfunc(&e, ParseFor),
-		)(code)
+	for {
+		tmp, e, err := stmt(code)
 
 		if err != nil {
-			return code, Block{Statements: stmts}, nil
+			if !code.empty() && (topLevel || !code.peek().Is(lexer.Symbol, "}")) {
+				// the statement is skipped so the errors in the rest of the code can be found too
+				code = skipStatement(code, topLevel, err)
+				continue
+			}
+
+			// the blocks inside of the statements were parsed first, so they already claimed their comments
+			comments := claimComments(start, code.stripWhitespace())
+			return code, Block{Span: spanBetween(start, code), Statements: stmts, Comments: comments}, nil
 		}
 
 		code = tmp
@@ -266,30 +560,64 @@ func ParseBlock(code string) (string, Block, error) {
 	}
 }
 
-// ParseCode parses an entire script. Fails if there is code leftover after parsing.
-func ParseCode(code string) (*Block, error) {
-	code, blk, err := ParseBlock(code)
-	if err != nil {
-		return nil, err
-	}
+// skipStatement reports the error of a statement which couldn't be parsed and skips it. The statement ends after the
+// next ";" or after the "}" of a block that started inside of the statement. A "}" which closes the surrounding block
+// isn't skipped unless the statement is on the top level, where there is no surrounding block.
+func skipStatement(code Code, topLevel bool, statementErr error) Code {
+	var err *ParseError
+	errors.As(statementErr, &err)
+
+	depth := 0
+	for !code.empty() {
+		token := code.peek()
+		if token.Is(lexer.Symbol, "}") && depth == 0 && !topLevel {
+			break
+		}
+		if token.Kind == lexer.Error {
+			// newParseError uses the message of the lexer
+			code.reportError(newParseError(code, ""))
+		}
+		code = code.next()
 
-	code = stripWhitespaceLeft(code)
-	if code != "" {
-		return nil, &ParseError{Message: "Couldn't continue parsing after: `" + code + "`"}
+		if token.Is(lexer.Symbol, "{") {
+			depth++
+		} else if token.Is(lexer.Symbol, "}") {
+			depth--
+		}
+		if depth <= 0 && token.Is(lexer.Symbol, "}") {
+			// the block could also have been a literal like "[]Int{}" which is followed by the ";" of the statement
+			if code.peek().Is(lexer.Symbol, ";") {
+				code = code.next()
+			}
+			break
+		}
+		if depth <= 0 && token.Is(lexer.Symbol, ";") {
+			break
+		}
 	}
 
-	return &blk, nil
+	err.Span.End = code.Position()
+	code.reportError(err)
+	return code
 }
 
-var stripWhitespaceRegex = regexp.MustCompile(`^\s+`)
+// ParseCode parses an entire script. Statements which can't be parsed are skipped, the returned error then contains
+// all syntax errors as ParseErrors. The block with the statements which could be parsed is returned anyway.
+func ParseCode(code string) (*Block, error) {
+	return ParseFile("", code)
+}
 
-// stripWhitespaceLeft strips all whitespace on the left of the string and returns a string without it.
-func stripWhitespaceLeft(s string) string {
-	loc := stripWhitespaceRegex.FindStringIndex(s)
+// ParseFile parses an entire script like ParseCode but also puts the name of the file into the positions of the
+// expressions and errors. A shebang line like "#!/usr/bin/env mbs" at the start of the script is skipped.
+func ParseFile(file, code string) (*Block, error) {
+	start := NewCode(file, code)
+	_, blk, _ := parseStatements(start, true)
 
-	if loc == nil {
-		return s
+	if errs := start.src.errors; len(errs) != 0 {
+		// errors in nested blocks are reported before the statements around them are skipped
+		sort.SliceStable(errs, func(i, j int) bool { return errs[i].Span.Start.Offset < errs[j].Span.Start.Offset })
+		return &blk, errs
 	}
 
-	return s[loc[1]:]
+	return &blk, nil
 }
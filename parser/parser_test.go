@@ -3,16 +3,18 @@ package parser
 import (
 	"fmt"
 	. "mbs/common"
+	"mbs/types"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 )
 
 func TestReadVar(t *testing.T) {
 	testCase := func(code, expectedCode, expectedName string) {
-		code, expr, err := ParseReadVar(code)
+		rest, expr, err := ParseReadVar(NewCode("", code))
 
-		checkErrorAndCompareExpressionsAndCode(t, err, expr, ReadVar{Name: expectedName}, code, expectedCode)
+		checkErrorAndCompareExpressionsAndCode(t, err, expr, ReadVar{Name: expectedName}, rest, expectedCode)
 	}
 
 	testCase("abc ", " ", "abc")
@@ -25,7 +27,7 @@ func TestReadVar(t *testing.T) {
 
 func TestReadVar_negative(t *testing.T) {
 	testCase := func(t *testing.T, code string) {
-		_, _, err := ParseName(code)
+		_, _, err := ParseName(NewCode("", code))
 
 		if err == nil {
 			t.Errorf(`expected error when parsing "%s"`, code)
@@ -37,6 +39,8 @@ func TestReadVar_negative(t *testing.T) {
 	testCase(t, "{ ")
 	testCase(t, "äzcxv")
 	testCase(t, "")
+	testCase(t, "if ")
+	testCase(t, "true")
 }
 
 func TestParseString(t *testing.T) {
@@ -44,9 +48,49 @@ func TestParseString(t *testing.T) {
 	expectedExpr := String{Data: "Hello World"}
 	expectedCode := "; b:=123;"
 
-	code, expr, err := ParseString(code)
+	rest, expr, err := ParseString(NewCode("", code))
 
-	checkErrorAndCompareExpressionsAndCode(t, err, expr, expectedExpr, code, expectedCode)
+	checkErrorAndCompareExpressionsAndCode(t, err, expr, expectedExpr, rest, expectedCode)
+}
+
+func TestParseString_escapes(t *testing.T) {
+	testCase := func(code, expected string) {
+		t.Run(code, func(t *testing.T) {
+			rest, expr, err := ParseString(NewCode("", code+"; x"))
+			checkErrorAndCompareExpressionsAndCode(t, err, expr, String{Data: expected}, rest, "; x")
+
+			// printing the string keeps the literal, without it the string is written as "..." which is parsed as the
+			// same string again
+			if err == nil {
+				if printed := expr.Print(); printed != code {
+					t.Errorf("got the literal %s wanted %s", printed, code)
+				}
+				printed := String{Data: expected}.Print()
+				rest, reparsed, err := ParseString(NewCode("", printed))
+				checkErrorAndCompareExpressionsAndCode(t, err, reparsed, expr, rest, "")
+			}
+		})
+	}
+
+	testCase(`"a\nb"`, "a\nb")
+	testCase("\"line\nbreak\"", "line\nbreak")
+	testCase(`"tab\there\r\n"`, "tab\there\r\n")
+	testCase(`"back\\slash"`, `back\slash`)
+	testCase(`"ends with \\"`, `ends with \`)
+	testCase(`"\"quoted\""`, `"quoted"`)
+	testCase(`"\u{48}\u{e9}\u{1F600}"`, "Hé😀")
+	testCase(`"\u{7}"`, "\a")
+	testCase("`raw \\n \"string\"\nwith lines`", "raw \\n \"string\"\nwith lines")
+	testCase(`""`, "")
+	testCase(`""""""`, "")
+	testCase(`"""multi
+line "quoted" \t"""`, "multi\nline \"quoted\" \t")
+
+	for _, code := range []string{`"a\qb"`, `"\u{}"`, `"\u{110000}"`, `"\u{48"`, `"unterminated`, "`raw", `"""open"`, `"\"`} {
+		if _, _, err := ParseString(NewCode("", code)); err == nil {
+			t.Errorf(`"%s" shouldn't be parsed`, code)
+		}
+	}
 }
 
 func TestParseBoolean(t *testing.T) {
@@ -54,9 +98,9 @@ func TestParseBoolean(t *testing.T) {
 	expectedExpr := Boolean{Data: false}
 	expectedCode := "; b:=123;"
 
-	code, expr, err := ParseBoolean(code)
+	rest, expr, err := ParseBoolean(NewCode("", code))
 
-	checkErrorAndCompareExpressionsAndCode(t, err, expr, expectedExpr, code, expectedCode)
+	checkErrorAndCompareExpressionsAndCode(t, err, expr, expectedExpr, rest, expectedCode)
 }
 
 func TestParseInteger(t *testing.T) {
@@ -64,9 +108,9 @@ func TestParseInteger(t *testing.T) {
 	expectedExpr := Integer{Data: 12345}
 	expectedCode := "; b:=123;"
 
-	code, expr, err := ParseInteger(code)
+	rest, expr, err := ParseInteger(NewCode("", code))
 
-	checkErrorAndCompareExpressionsAndCode(t, err, expr, expectedExpr, code, expectedCode)
+	checkErrorAndCompareExpressionsAndCode(t, err, expr, expectedExpr, rest, expectedCode)
 }
 
 func TestParseFloat(t *testing.T) {
@@ -74,9 +118,9 @@ func TestParseFloat(t *testing.T) {
 	expectedExpr := Float{Data: 123.51}
 	expectedCode := "; b:=123;"
 
-	code, expr, err := ParseFloat(code)
+	rest, expr, err := ParseFloat(NewCode("", code))
 
-	checkErrorAndCompareExpressionsAndCode(t, err, expr, expectedExpr, code, expectedCode)
+	checkErrorAndCompareExpressionsAndCode(t, err, expr, expectedExpr, rest, expectedCode)
 }
 
 func TestParseOperator(t *testing.T) {
@@ -86,25 +130,344 @@ func TestParseOperator(t *testing.T) {
 	expectedExpr := Operator{Symbol: "+", FirstExp: firstExpr, SecondExp: secondExpr}
 	expectedCode := "; b:=123;"
 
-	code, expr, err := ParseOperator(code)
+	rest, expr, err := ParseExpression(NewCode("", code))
+
+	checkErrorAndCompareExpressionsAndCode(t, err, expr, expectedExpr, rest, expectedCode)
+}
+
+func TestParseOperatorPrecedence(t *testing.T) {
+	op := func(symbol string, first, second Expr) Operator {
+		return Operator{Symbol: symbol, FirstExp: first, SecondExp: second}
+	}
+	a, b, c := ReadVar{Name: "a"}, ReadVar{Name: "b"}, ReadVar{Name: "c"}
+
+	// every level binds stronger than the one before
+	testParseExpression(t, "a || b && c", op("||", a, op("&&", b, c)), "")
+	testParseExpression(t, "a && b || c", op("||", op("&&", a, b), c), "")
+	testParseExpression(t, "a && b == c", op("&&", a, op("==", b, c)), "")
+	testParseExpression(t, "a != b && c", op("&&", op("!=", a, b), c), "")
+	testParseExpression(t, "a == b < c", op("==", a, op("<", b, c)), "")
+	testParseExpression(t, "a >= b != c", op("!=", op(">=", a, b), c), "")
+	testParseExpression(t, "a <= b + c", op("<=", a, op("+", b, c)), "")
+	testParseExpression(t, "a - b > c", op(">", op("-", a, b), c), "")
+	testParseExpression(t, "a + b * c", op("+", a, op("*", b, c)), "")
+	testParseExpression(t, "a / b - c", op("-", op("/", a, b), c), "")
+
+	// operators on the same level are left associative
+	testParseExpression(t, "a || b || c", op("||", op("||", a, b), c), "")
+	testParseExpression(t, "a && b && c", op("&&", op("&&", a, b), c), "")
+	testParseExpression(t, "a == b != c", op("!=", op("==", a, b), c), "")
+	testParseExpression(t, "a < b >= c", op(">=", op("<", a, b), c), "")
+	testParseExpression(t, "a - b + c", op("+", op("-", a, b), c), "")
+	testParseExpression(t, "a / b * c", op("*", op("/", a, b), c), "")
+
+	// parentheses override the precedence
+	testParseExpression(t, "(a + b) * c", op("*", op("+", a, b), c), "")
+	testParseExpression(t, "a - (b - c)", op("-", a, op("-", b, c)), "")
+
+	testParseExpression(t, "x > 1 && y < 2; z", op("&&",
+		op(">", ReadVar{Name: "x"}, Integer{Data: 1}),
+		op("<", ReadVar{Name: "y"}, Integer{Data: 2})), "; z")
+	testParseExpression(t, "1 - 2 * 3 - 4", op("-", op("-", Integer{Data: 1}, op("*", Integer{Data: 2}, Integer{Data: 3})), Integer{Data: 4}), "")
+
+	testParseExpressionNegative(t, "a +")
+	testParseExpressionNegative(t, "a + * b")
+}
+
+func TestParseUnaryOperator(t *testing.T) {
+	x, y := ReadVar{Name: "x"}, ReadVar{Name: "y"}
+
+	testParseExpression(t, "!done", UnaryOperator{Symbol: "!", Exp: ReadVar{Name: "done"}}, "")
+	testParseExpression(t, "-x; y", UnaryOperator{Symbol: "-", Exp: x}, "; y")
+	testParseExpression(t, "- 5", UnaryOperator{Symbol: "-", Exp: Integer{Data: 5}}, "")
+	testParseExpression(t, "-(1.5)", UnaryOperator{Symbol: "-", Exp: Float{Data: 1.5}}, "")
+	testParseExpression(t, "!!x", UnaryOperator{Symbol: "!", Exp: UnaryOperator{Symbol: "!", Exp: x}}, "")
+	testParseExpression(t, "--x", UnaryOperator{Symbol: "-", Exp: UnaryOperator{Symbol: "-", Exp: x}}, "")
+	testParseExpression(t, "-f()", UnaryOperator{Symbol: "-", Exp: FunctionCall{Name: "f", Arguments: []Expr{}}}, "")
+
+	// negative numbers are still literals
+	testParseExpression(t, "-5", Integer{Data: -5}, "")
+	testParseExpression(t, "x - -5", Operator{Symbol: "-", FirstExp: x, SecondExp: Integer{Data: -5}}, "")
+
+	// unary operators bind stronger than binary operators
+	testParseExpression(t, "-x * y", Operator{Symbol: "*", FirstExp: UnaryOperator{Symbol: "-", Exp: x}, SecondExp: y}, "")
+	testParseExpression(t, "x - -y", Operator{Symbol: "-", FirstExp: x, SecondExp: UnaryOperator{Symbol: "-", Exp: y}}, "")
+	testParseExpression(t, "!x && y", Operator{Symbol: "&&", FirstExp: UnaryOperator{Symbol: "!", Exp: x}, SecondExp: y}, "")
+	testParseExpression(t, "!(x && y)", UnaryOperator{Symbol: "!", Exp: Operator{Symbol: "&&", FirstExp: x, SecondExp: y}}, "")
+
+	testParseExpressionNegative(t, "!")
+	testParseExpressionNegative(t, "-")
+	testParseExpressionNegative(t, "!*x")
+}
+
+func ExampleOperator_Print() {
+	for _, code := range []string{"a + b * c", "(a + b) * c", "a - (b - c)", "(a - b) - c", "(a || b) && !c", "!(a && b)", "-a * -(b + c)"} {
+		_, expr, _ := ParseExpression(NewCode("", code))
+		fmt.Println(expr.Print())
+	}
+
+	// Output:
+	// a + b * c
+	// (a + b) * c
+	// a - (b - c)
+	// a - b - c
+	// (a || b) && !c
+	// !(a && b)
+	// -a * -(b + c)
+}
+
+func TestParseList(t *testing.T) {
+	testParseExpression(t, "[1, 2, 3]; x", List{ElemType: types.Nop, Elems: []Expr{Integer{Data: 1}, Integer{Data: 2}, Integer{Data: 3}}}, "; x")
+	testParseExpression(t, "[]", List{ElemType: types.Nop, Elems: []Expr{}}, "")
+	testParseExpression(t, "[a + 1]", List{ElemType: types.Nop, Elems: []Expr{
+		Operator{Symbol: "+", FirstExp: ReadVar{Name: "a"}, SecondExp: Integer{Data: 1}},
+	}}, "")
+	testParseExpression(t, "[]Int{}", List{ElemType: types.Int, Elems: []Expr{}}, "")
+	testParseExpression(t, "[]String{\"a\", \"b\"}", List{ElemType: types.String, Elems: []Expr{String{Data: "a"}, String{Data: "b"}}}, "")
+	testParseExpression(t, "[][]Int{[1]}", List{ElemType: types.List{Elem: types.Int}, Elems: []Expr{
+		List{ElemType: types.Nop, Elems: []Expr{Integer{Data: 1}}},
+	}}, "")
+
+	testParseExpressionNegative(t, "[1, 2")
+	testParseExpressionNegative(t, "[1 2]")
+}
+
+func TestParseIndex(t *testing.T) {
+	xs, i := ReadVar{Name: "xs"}, ReadVar{Name: "i"}
+
+	testParseExpression(t, "xs[i]; x", Index{Exp: xs, Index: i}, "; x")
+	testParseExpression(t, "xs[i][0]", Index{Exp: Index{Exp: xs, Index: i}, Index: Integer{Data: 0}}, "")
+	testParseExpression(t, "xs[i + 1]", Index{Exp: xs, Index: Operator{Symbol: "+", FirstExp: i, SecondExp: Integer{Data: 1}}}, "")
+	testParseExpression(t, "f()[0]", Index{Exp: FunctionCall{Name: "f", Arguments: []Expr{}}, Index: Integer{Data: 0}}, "")
+	testParseExpression(t, "[1, 2][1]", Index{Exp: List{ElemType: types.Nop, Elems: []Expr{Integer{Data: 1}, Integer{Data: 2}}}, Index: Integer{Data: 1}}, "")
+	testParseExpression(t, "-xs[0]", UnaryOperator{Symbol: "-", Exp: Index{Exp: xs, Index: Integer{Data: 0}}}, "")
+	testParseExpression(t, "xs[0] * 2", Operator{Symbol: "*", FirstExp: Index{Exp: xs, Index: Integer{Data: 0}}, SecondExp: Integer{Data: 2}}, "")
+}
+
+func TestParseWriteIndex(t *testing.T) {
+	testCase := func(code string, expectedExpr Expr, expectedCode string) {
+		t.Run(code, func(t *testing.T) {
+			rest, expr, err := ParseWriteIndex(NewCode("", code))
+
+			checkErrorAndCompareExpressionsAndCode(t, err, expr, expectedExpr, rest, expectedCode)
+		})
+	}
+	xs := ReadVar{Name: "xs"}
+
+	testCase("xs[0] = 1;", WriteIndex{Exp: xs, Index: Integer{Data: 0}, Value: Integer{Data: 1}}, ";")
+	testCase("xs[0][1] = a + 1", WriteIndex{Exp: Index{Exp: xs, Index: Integer{Data: 0}}, Index: Integer{Data: 1}, Value: Operator{
+		Symbol: "+", FirstExp: ReadVar{Name: "a"}, SecondExp: Integer{Data: 1},
+	}}, "")
+
+	if _, _, err := ParseWriteIndex(NewCode("", "xs = 1")); err == nil {
+		t.Error("expected error when assigning something which is not an element of a list")
+	}
+}
+
+func TestParseTypeName(t *testing.T) {
+	testCase := func(code string, expectedType types.Type, expectedCode string) {
+		rest, tipe, err := ParseTypeName(NewCode("", code))
+
+		if err != nil || tipe != expectedType || rest.String() != expectedCode {
+			t.Errorf(`got ("%s", %v, %v) wanted ("%s", %v, nil)`, rest, tipe, err, expectedCode, expectedType)
+		}
+	}
+
+	testCase("Int", types.Int, "")
+	testCase(" Float {", types.Float, " {")
+	testCase("String", types.String, "")
+	testCase("Boolean", types.Boolean, "")
+	testCase("[]Int", types.List{Elem: types.Int}, "")
+	testCase("[] [] String)", types.List{Elem: types.List{Elem: types.String}}, ")")
+	testCase("map[String]Int", types.Map{Key: types.String, Value: types.Int}, "")
+	testCase("map[Int]map[String][]Float {", types.Map{Key: types.Int, Value: types.Map{Key: types.String, Value: types.List{Elem: types.Float}}}, " {")
+	testCase("Point", types.Struct{Name: "Point"}, "")
+	testCase("map[String][]Point", types.Map{Key: types.String, Value: types.List{Elem: types.Struct{Name: "Point"}}}, "")
+
+	for _, code := range []string{"[]", "[Int]", "1", ""} {
+		if _, _, err := ParseTypeName(NewCode("", code)); err == nil {
+			t.Errorf(`expected error when parsing "%s"`, code)
+		}
+	}
+}
+
+func TestParseMap(t *testing.T) {
+	testParseExpression(t, "map[String]Int{}; x", Map{KeyType: types.String, ValueType: types.Int, Entries: []MapEntry{}}, "; x")
+	testParseExpression(t, "map[String]Int{\"a\": 1, \"b\": x + 1}", Map{KeyType: types.String, ValueType: types.Int, Entries: []MapEntry{
+		{Key: String{Data: "a"}, Value: Integer{Data: 1}},
+		{Key: String{Data: "b"}, Value: Operator{Symbol: "+", FirstExp: ReadVar{Name: "x"}, SecondExp: Integer{Data: 1}}},
+	}}, "")
+	testParseExpression(t, "map[Int][]Int{1: [2]}[1]", Index{
+		Exp: Map{KeyType: types.Int, ValueType: types.List{Elem: types.Int}, Entries: []MapEntry{
+			{Key: Integer{Data: 1}, Value: List{ElemType: types.Nop, Elems: []Expr{Integer{Data: 2}}}},
+		}},
+		Index: Integer{Data: 1},
+	}, "")
+	testParseExpression(t, "m[\"a\"]", Index{Exp: ReadVar{Name: "m"}, Index: String{Data: "a"}}, "")
+	testParseExpression(t, "mapping[0]", Index{Exp: ReadVar{Name: "mapping"}, Index: Integer{Data: 0}}, "")
+
+	for _, code := range []string{"map[String]Int{\"a\" 1}", "map[String]{}", "map[String]Int{\"a\": 1,}"} {
+		if _, _, err := ParseMap(NewCode("", code)); err == nil {
+			t.Errorf(`expected error when parsing "%s"`, code)
+		}
+	}
+}
+
+func TestParseStruct(t *testing.T) {
+	p := ReadVar{Name: "p"}
+
+	testParseExpression(t, "Point{x: 1, y: a + 1}; z", Struct{Name: "Point", Fields: []FieldValue{
+		{Name: "x", Value: Integer{Data: 1}},
+		{Name: "y", Value: Operator{Symbol: "+", FirstExp: ReadVar{Name: "a"}, SecondExp: Integer{Data: 1}}},
+	}}, "; z")
+	testParseExpression(t, "Empty{}", Struct{Name: "Empty", Fields: []FieldValue{}}, "")
+	testParseExpression(t, "p.x", Field{Exp: p, Name: "x"}, "")
+	testParseExpression(t, "p.from.x * 2", Operator{Symbol: "*", FirstExp: Field{Exp: Field{Exp: p, Name: "from"}, Name: "x"}, SecondExp: Integer{Data: 2}}, "")
+	testParseExpression(t, "ps[0].x", Field{Exp: Index{Exp: ReadVar{Name: "ps"}, Index: Integer{Data: 0}}, Name: "x"}, "")
+	testParseExpression(t, "p.xs[0]", Index{Exp: Field{Exp: p, Name: "xs"}, Index: Integer{Data: 0}}, "")
+	testParseExpression(t, "Point{x: 1, y: 2}.x", Field{Exp: Struct{Name: "Point", Fields: []FieldValue{
+		{Name: "x", Value: Integer{Data: 1}},
+		{Name: "y", Value: Integer{Data: 2}},
+	}}, Name: "x"}, "")
+}
+
+func TestParseStructDecl(t *testing.T) {
+	testCase := func(code string, expectedExpr Expr, expectedCode string) {
+		t.Run(code, func(t *testing.T) {
+			rest, expr, err := ParseStructDecl(NewCode("", code))
+
+			checkErrorAndCompareExpressionsAndCode(t, err, expr, expectedExpr, rest, expectedCode)
+		})
+	}
+
+	testCase("type Point struct { x Int; y Int } z", StructDecl{Name: "Point", Fields: []FieldDecl{
+		{Name: "x", Type: types.Int},
+		{Name: "y", Type: types.Int},
+	}}, " z")
+	testCase("type Line struct {\n\tfrom Point;\n\ttags []String;\n}", StructDecl{Name: "Line", Fields: []FieldDecl{
+		{Name: "from", Type: types.Struct{Name: "Point"}},
+		{Name: "tags", Type: types.List{Elem: types.String}},
+	}}, "")
+	testCase("type Empty struct {}", StructDecl{Name: "Empty", Fields: []FieldDecl{}}, "")
+
+	for _, code := range []string{"type Point { x Int }", "type Point struct { x }", "type Point struct { x Int y Int }", "typePoint struct {}"} {
+		if _, _, err := ParseStructDecl(NewCode("", code)); err == nil {
+			t.Errorf(`expected error when parsing "%s"`, code)
+		}
+	}
+}
 
-	checkErrorAndCompareExpressionsAndCode(t, err, expr, expectedExpr, code, expectedCode)
+func TestParseWriteField(t *testing.T) {
+	rest, expr, err := ParseWriteField(NewCode("", "p.from.x = 3;"))
+	checkErrorAndCompareExpressionsAndCode(t, err, expr, WriteField{
+		Exp:   Field{Exp: ReadVar{Name: "p"}, Name: "from"},
+		Name:  "x",
+		Value: Integer{Data: 3},
+	}, rest, ";")
+
+	if _, _, err := ParseWriteField(NewCode("", "xs[0] = 1")); err == nil {
+		t.Error("expected error when assigning something which is not a field")
+	}
+}
+
+func ExampleStructDecl_Print() {
+	block, _ := ParseCode(`type Point struct { x Int; y Int }
+p = Point{x: 1, y: 2};
+p.x = p.y;`)
+	fmt.Println(block.Print())
+
+	// Output:
+	// type Point struct {
+	// x Int
+	// y Int
+	// }
+	//
+	// p = Point{x: 1, y: 2}
+	// p.x = p.y
+}
+
+func ExampleList_Print() {
+	_, expr, _ := ParseExpression(NewCode("", "[[]Int{}, [1, 2]][1][0]"))
+	fmt.Println(expr.Print())
+	_, expr, _ = ParseExpression(NewCode("", "map[String][]Int{\"a\": [1], \"b\": []Int{}}"))
+	fmt.Println(expr.Print())
+
+	// Output:
+	// [[]Int{}, [1, 2]][1][0]
+	// map[String][]Int{"a": [1], "b": []Int{}}
 }
 
 func TestParseFunctionCall(t *testing.T) {
 	// TODO: switch order of arguments
 	testCase := func(code string, expectedExpr Expr, expectedCode string) {
-		code, expr, err := ParseFunctionCall(code)
+		rest, expr, err := ParseFunctionCall(NewCode("", code))
+
+		checkErrorAndCompareExpressionsAndCode(t, err, expr, expectedExpr, rest, expectedCode)
+	}
+
+	testCase("asdf(123); b:=123;", FunctionCall{Name: "asdf", Arguments: []Expr{Integer{Data: 123}}}, "; b:=123;")
+	testCase("asdf(); b:=123;", FunctionCall{Name: "asdf", Arguments: []Expr{}}, "; b:=123;")
+	testCase("asdf(1, b, \"c\")", FunctionCall{Name: "asdf", Arguments: []Expr{Integer{Data: 1}, ReadVar{Name: "b"}, String{Data: "c"}}}, "")
+}
+
+func TestParseFunctionDecl(t *testing.T) {
+	testCase := func(code string, expectedExpr Expr, expectedCode string) {
+		t.Run(code, func(t *testing.T) {
+			rest, expr, err := ParseFunctionDecl(NewCode("", code))
+
+			checkErrorAndCompareExpressionsAndCode(t, err, expr, expectedExpr, rest, expectedCode)
+		})
+	}
+
+	testCase("func f() {}", FunctionDecl{Name: "f", Params: []Param{}, Returns: types.Nop, Body: Block{Statements: []Expr{}}}, "")
+	testCase("func add(a Int, b Float) Float { return a + b; } x", FunctionDecl{
+		Name:    "add",
+		Params:  []Param{{Name: "a", Type: types.Int}, {Name: "b", Type: types.Float}},
+		Returns: types.Float,
+		Body: Block{Statements: []Expr{
+			Return{Expr: Operator{Symbol: "+", FirstExp: ReadVar{Name: "a"}, SecondExp: ReadVar{Name: "b"}}},
+		}},
+	}, " x")
+	testCase("func greet(name String) { println(name); return; }", FunctionDecl{
+		Name:    "greet",
+		Params:  []Param{{Name: "name", Type: types.String}},
+		Returns: types.Nop,
+		Body: Block{Statements: []Expr{
+			FunctionCall{Name: "println", Arguments: []Expr{ReadVar{Name: "name"}}},
+			Return{Expr: Nop{}},
+		}},
+	}, "")
+}
+
+func TestParseFunctionDecl_negative(t *testing.T) {
+	testCase := func(code string) {
+		t.Run(code, func(t *testing.T) {
+			_, _, err := ParseFunctionDecl(NewCode("", code))
 
-		checkErrorAndCompareExpressionsAndCode(t, err, expr, expectedExpr, code, expectedCode)
+			if err == nil {
+				t.Errorf(`expected error when parsing "%s"`, code)
+			}
+		})
 	}
 
-	testCase("asdf(123); b:=123;", FunctionCall{Name: "asdf", Argument: Integer{Data: 123}}, "; b:=123;")
+	testCase("funcf() {}")
+	testCase("func f(a) {}")
+	testCase("func f(a 1) {}")
+	testCase("func f() 1 {}")
+	testCase("func f(a Int,) {}")
+}
+
+func TestParseCode_nestedFunctionDecl(t *testing.T) {
+	_, err := ParseCode("if (true) { func f() {} }")
+
+	if err == nil {
+		t.Error("expected error when declaring a function inside of a block")
+	}
 }
 
 func ExampleParseFunctionCall_nested() {
-	code, expr, _ := ParseFunctionCall(" a ( b ( c ( 123 ) ) ); x")
-	fmt.Println("code=" + code)
+	rest, expr, _ := ParseFunctionCall(NewCode("", " a ( b ( c ( 123 ) ) ); x"))
+	fmt.Println("code=" + rest.String())
 	if expr != nil {
 		fmt.Println("expr=" + expr.Print())
 	}
@@ -115,8 +478,8 @@ func ExampleParseFunctionCall_nested() {
 }
 
 func ExampleParseFunctionCall_complicated() {
-	code, expr, _ := ParseFunctionCall(" a ( b + 123 ) )")
-	fmt.Println("code=" + code)
+	rest, expr, _ := ParseFunctionCall(NewCode("", " a ( b + 123 ) )"))
+	fmt.Println("code=" + rest.String())
 	if expr != nil {
 		fmt.Println("expr=" + expr.Print())
 	}
@@ -138,6 +501,8 @@ func TestParseExpression(t *testing.T) {
 	testParseExpression(t, "5*2; b:=123;", Operator{Symbol: "*", FirstExp: Integer{Data: 5}, SecondExp: Integer{Data: 2}}, "; b:=123;")
 	testParseExpression(t, "abc", ReadVar{Name: "abc"}, "")
 	testParseExpression(t, "abc\"", ReadVar{Name: "abc"}, `"`)
+	testParseExpression(t, "iffy", ReadVar{Name: "iffy"}, "")
+	testParseExpression(t, "trueish", ReadVar{Name: "trueish"}, "")
 	// TODO
 	// testParseExpression(t, "print("\""Hello"\""), ...)
 	testParseExpressionNegative(t, "*")
@@ -148,30 +513,34 @@ func TestParseExpression(t *testing.T) {
 
 func testParseExpression(t *testing.T, expression string, expectedExpression Expr, expectedCode string) {
 	t.Run(expression, func(t *testing.T) {
-		code, expr, err := ParseExpression(expression)
-		checkErrorAndCompareExpressionsAndCode(t, err, expr, expectedExpression, code, expectedCode)
+		rest, expr, err := ParseExpression(NewCode("", expression))
+		checkErrorAndCompareExpressionsAndCode(t, err, expr, expectedExpression, rest, expectedCode)
 	})
 }
 
 func testParseExpressionNegative(t *testing.T, expression string) {
 	t.Run(expression, func(t *testing.T) {
-		_, expr, err := ParseExpression(expression)
+		_, expr, err := ParseExpression(NewCode("", expression))
 		if err == nil {
 			t.Errorf(`got (%+v) wanted nil `, expr)
 		}
 	})
 }
 
-func checkErrorAndCompareExpressionsAndCode(t *testing.T, err error, expr Expr, expectedExpr Expr, code string, expectedCode string) {
+// ignoreSource compares expressions without the positions, which are checked separately in TestPositions, and the
+// literals of strings, which are checked in TestParseString_escapes.
+var ignoreSource = cmp.Options{cmpopts.IgnoreTypes(Span{}), cmpopts.IgnoreFields(String{}, "Literal")}
+
+func checkErrorAndCompareExpressionsAndCode(t *testing.T, err error, expr Expr, expectedExpr Expr, code Code, expectedCode string) {
 	if err != nil {
 		t.Error(err)
 	}
 
-	if !cmp.Equal(expr, expectedExpr) {
+	if !cmp.Equal(expr, expectedExpr, ignoreSource) {
 		t.Errorf(`got (Expr: "%#v") wanted (Expr: "%#v")`, expr, expectedExpr)
 	}
 
-	if code != expectedCode {
+	if code.String() != expectedCode {
 		t.Errorf(`got (Code: "%s") wanted (Code: "%s")`, code, expectedCode)
 	}
 }
@@ -180,17 +549,17 @@ func TestParseWriteVar(t *testing.T) {
 	expectedCode := " ; b = 456  ;  \n\r c = 546;"
 	expectedExpr := Integer{Data: 123}
 
-	code, expr, err := ParseWriteVar(" a = 123 ; b = 456  ;  \n\r c = 546;")
+	rest, expr, err := ParseWriteVar(NewCode("", " a = 123 ; b = 456  ;  \n\r c = 546;"))
 
-	if code != expectedCode || expr == nil || err != nil {
-		t.Errorf(`got (Code: "%s", Expr: "%s", Err: %s) wanted ("%s", "%+v", nil)`, code, expr, err, expectedCode, expectedExpr)
+	if rest.String() != expectedCode || expr == nil || err != nil {
+		t.Errorf(`got (Code: "%s", Expr: "%s", Err: %s) wanted ("%s", "%+v", nil)`, rest, expr, err, expectedCode, expectedExpr)
 	}
 
 	if writeVar, ok := expr.(WriteVar); ok {
 		if writeVar.Name != expectedName || writeVar.Expr == nil {
 			t.Errorf(`got (Name: "%s", Expr: nil) wanted (Name: "%s", Expr: "%+v")`, writeVar.Name, expectedName, expectedExpr)
 		}
-		if !cmp.Equal(writeVar.Expr, expectedExpr) {
+		if !cmp.Equal(writeVar.Expr, expectedExpr, ignoreSource) {
 			t.Errorf(`got (Expr: "%s") wanted (Expr: "%+v")`, writeVar.Expr, expectedExpr)
 		}
 	} else {
@@ -200,22 +569,77 @@ func TestParseWriteVar(t *testing.T) {
 
 func TestParseParentheses(t *testing.T) {
 	testCase := func(code string, expectedExpr Expr, expectedCode string) {
-		code, expr, err := ParseParentheses(code)
+		rest, expr, err := ParseParentheses(NewCode("", code))
 
-		checkErrorAndCompareExpressionsAndCode(t, err, expr, expectedExpr, code, expectedCode)
+		checkErrorAndCompareExpressionsAndCode(t, err, expr, expectedExpr, rest, expectedCode)
 	}
 
 	testCase("(123)", Integer{Data: 123}, "")
 	testCase("(123);123", Integer{Data: 123}, ";123")
-	testCase("(asdf(123));123", FunctionCall{Name: "asdf", Argument: Integer{Data: 123}}, ";123")
+	testCase("(asdf(123));123", FunctionCall{Name: "asdf", Arguments: []Expr{Integer{Data: 123}}}, ";123")
+}
+
+func TestParseIf(t *testing.T) {
+	testCase := func(code string, expectedExpr Expr, expectedCode string) {
+		t.Run(code, func(t *testing.T) {
+			rest, expr, err := ParseIf(NewCode("", code))
+
+			checkErrorAndCompareExpressionsAndCode(t, err, expr, expectedExpr, rest, expectedCode)
+		})
+	}
+	a, b := ReadVar{Name: "a"}, ReadVar{Name: "b"}
+	call := func(name string) Block {
+		return Block{Statements: []Expr{FunctionCall{Name: name, Arguments: []Expr{}}}}
+	}
+
+	testCase("if (a) { x(); } y", If{Condition: a, Body: call("x")}, " y")
+	testCase("if (a) { x(); } else { y(); }", If{Condition: a, Body: call("x"), Else: &Block{Statements: []Expr{
+		FunctionCall{Name: "y", Arguments: []Expr{}},
+	}}}, "")
+	testCase("if (a) { x(); } else if (b) { y(); } else { z(); }", If{Condition: a, Body: call("x"), Else: &Block{Statements: []Expr{
+		If{Condition: b, Body: call("y"), Else: &Block{Statements: []Expr{
+			FunctionCall{Name: "z", Arguments: []Expr{}},
+		}}},
+	}}}, "")
+	testCase("if (a) { x(); } elsewhere = 1;", If{Condition: a, Body: call("x")}, " elsewhere = 1;")
+
+	// a name which starts with a keyword is still a name
+	if _, _, err := ParseIf(NewCode("", "iffy (a) { x(); }")); err == nil {
+		t.Error(`expected error when parsing "iffy (a) { x(); }"`)
+	}
+}
+
+func ExampleParseIf() {
+	_, expr, err := ParseIf(NewCode("", `if (a == 1) {
+		println("one");
+	} else if (a == 2) {
+		println("two");
+	} else {
+		println("many");
+	}`))
+
+	if err != nil {
+		fmt.Println("ERROR", err)
+	} else {
+		fmt.Println(expr.Print())
+	}
+
+	// Output:
+	// if (a == 1) {
+	// println("one")
+	// } else if (a == 2) {
+	// println("two")
+	// } else {
+	// println("many")
+	// }
 }
 
 func TestParseFor(t *testing.T) {
 	testCase := func(code string, expectedExpr Expr, expectedCode string) {
 		t.Run(code, func(t *testing.T) {
-			code, expr, err := ParseFor(code)
+			rest, expr, err := ParseFor(NewCode("", code))
 
-			checkErrorAndCompareExpressionsAndCode(t, err, expr, expectedExpr, code, expectedCode)
+			checkErrorAndCompareExpressionsAndCode(t, err, expr, expectedExpr, rest, expectedCode)
 		})
 	}
 
@@ -227,10 +651,63 @@ func TestParseFor(t *testing.T) {
 	}, "")
 }
 
+func TestParseWhile(t *testing.T) {
+	testCase := func(code string, expectedExpr Expr, expectedCode string) {
+		t.Run(code, func(t *testing.T) {
+			rest, expr, err := ParseWhile(NewCode("", code))
+
+			checkErrorAndCompareExpressionsAndCode(t, err, expr, expectedExpr, rest, expectedCode)
+		})
+	}
+
+	testCase("while (a) {} x", While{Condition: ReadVar{Name: "a"}, Body: Block{Statements: []Expr{}}}, " x")
+	testCase("outer: while (true) { break; continue outer; }", While{
+		Label:     "outer",
+		Condition: Boolean{Data: true},
+		Body:      Block{Statements: []Expr{Break{}, Continue{Label: "outer"}}},
+	}, "")
+}
+
+func TestParseFor_label(t *testing.T) {
+	rest, expr, err := ParseFor(NewCode("", "outer: for (;true;) { break outer; }"))
+
+	checkErrorAndCompareExpressionsAndCode(t, err, expr, For{
+		Label:       "outer",
+		Init:        &Nop{},
+		Condition:   Boolean{Data: true},
+		Advancement: &Nop{},
+		Body:        Block{Statements: []Expr{Break{Label: "outer"}}},
+	}, rest, "")
+}
+
+func ExampleParseWhile() {
+	_, expr, err := ParseWhile(NewCode("", `loop: while (a < 10) {
+		if (a == 5) {
+			break loop;
+		}
+		continue;
+	}`))
+
+	if err != nil {
+		fmt.Println("ERROR", err)
+	} else {
+		fmt.Println(expr.Print())
+	}
+
+	// Output:
+	// loop: while (a < 10) {
+	// if (a == 5) {
+	// break loop
+	// }
+	//
+	// continue
+	// }
+}
+
 func ExampleParseFor() {
-	_, expr, err := ParseFor(`for (e = 1; e < 4; e = e + 1) {
+	_, expr, err := ParseFor(NewCode("", `for (e = 1; e < 4; e = e + 1) {
 		print("e");
-	}`)
+	}`))
 
 	if err != nil {
 		fmt.Println("ERROR", err)
@@ -262,7 +739,7 @@ d = 4.2;`
 	// a = 123
 	// b = "abc"
 	// c = true
-	// d = 4.20000
+	// d = 4.2
 }
 
 func ExampleParseCode_full() {
@@ -311,7 +788,7 @@ print(input);`
 	// a = 123
 	// b = "abc"
 	// c = true
-	// d = 4.20000
+	// d = 4.2
 	// if (c) {
 	// print("c is true")
 	// }
@@ -334,6 +811,229 @@ print(input);`
 	// for (e = 1; e < 4; e = e + 1) {
 	// print("e")
 	// }
-	// input = readline(nop)
+	// input = readline()
 	// print(input)
 }
+
+func TestPositions(t *testing.T) {
+	block, err := ParseFile("test.mbs", "a = 1;\nif (a == 1) {\n\tprintln(\"x\" + a);\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pos := func(line, column, offset int) Position {
+		return Position{File: "test.mbs", Line: line, Column: column, Offset: offset}
+	}
+	testCase := func(expr Expr, start, end Position) {
+		t.Run(expr.Print(), func(t *testing.T) {
+			if expr.Pos() != (Span{Start: start, End: end}) {
+				t.Errorf(`got (%v - %v) wanted (%v - %v)`, expr.Pos().Start, expr.Pos().End, start, end)
+			}
+		})
+	}
+
+	writeVar := block.Statements[0].(WriteVar)
+	if_ := block.Statements[1].(If)
+	call := if_.Body.Statements[0].(FunctionCall)
+	testCase(block, pos(1, 1, 0), pos(4, 2, 41))
+	testCase(writeVar, pos(1, 1, 0), pos(1, 6, 5))
+	testCase(writeVar.Expr, pos(1, 5, 4), pos(1, 6, 5))
+	testCase(if_, pos(2, 1, 7), pos(4, 2, 41))
+	testCase(if_.Condition, pos(2, 5, 11), pos(2, 11, 17))
+	testCase(if_.Body, pos(3, 2, 22), pos(3, 19, 39))
+	testCase(call, pos(3, 2, 22), pos(3, 18, 38))
+	testCase(call.Arguments[0], pos(3, 10, 30), pos(3, 17, 37))
+}
+
+func TestParseError_position(t *testing.T) {
+	_, err := ParseFile("test.mbs", "a = 1;\n  b = ;")
+
+	parseErrs, ok := err.(ParseErrors)
+	if !ok || len(parseErrs) != 1 {
+		t.Fatalf("got %v wanted one ParseError", err)
+	}
+	if parseErrs[0].Span.Start != (Position{File: "test.mbs", Line: 2, Column: 7, Offset: 13}) {
+		t.Errorf("got %v wanted test.mbs:2:7", parseErrs[0].Span.Start)
+	}
+	if parseErrs[0].Span.End != (Position{File: "test.mbs", Line: 2, Column: 8, Offset: 14}) {
+		t.Errorf("got %v wanted test.mbs:2:8", parseErrs[0].Span.End)
+	}
+	if err.Error() != "test.mbs:2:7: expected expression, found ';'" {
+		t.Errorf(`got "%s"`, err.Error())
+	}
+}
+
+func TestParseCode_recovery(t *testing.T) {
+	testCase := func(code string, expectedStatements []Expr, expectedErrors ...string) {
+		t.Run(code, func(t *testing.T) {
+			block, err := ParseCode(code)
+
+			var messages []string
+			if err != nil {
+				for _, parseErr := range err.(ParseErrors) {
+					messages = append(messages, parseErr.Error())
+				}
+			}
+			if !cmp.Equal(messages, expectedErrors) {
+				t.Errorf("got errors %q wanted %q", messages, expectedErrors)
+			}
+			if !cmp.Equal(block.Statements, expectedStatements, ignoreSource) {
+				t.Errorf(`got (Statements: "%#v") wanted (Statements: "%#v")`, block.Statements, expectedStatements)
+			}
+		})
+	}
+	write := func(name string, value int64) Expr {
+		return WriteVar{Name: name, Expr: Integer{Data: value}}
+	}
+
+	testCase("a = 1; b = ; c = 3; d = * 2; e = 5;", []Expr{write("a", 1), write("c", 3), write("e", 5)},
+		"line 1:12: expected expression, found ';'", "line 1:25: expected expression, found '*'")
+	// the "}" which ends the body of the if isn't skipped together with the broken statement
+	testCase("if (true) { a = ; b = 2; } c = 3;", []Expr{
+		If{Condition: Boolean{Data: true}, Body: Block{Statements: []Expr{write("b", 2)}}},
+		write("c", 3),
+	}, "line 1:17: expected expression, found ';'")
+	testCase("if (true) { a = 1 } c = 3;", []Expr{
+		If{Condition: Boolean{Data: true}, Body: Block{Statements: []Expr{}}},
+		write("c", 3),
+	}, "line 1:19: expected ';' or operator, found '}'")
+	// a broken statement which contains a block is skipped up to the end of that block
+	testCase("while (true { a = 1; } b = 2;", []Expr{write("b", 2)}, "line 1:13: expected ')' or operator, found '{'")
+	testCase("a = 1; } b = 2;", []Expr{write("a", 1), write("b", 2)}, "line 1:8: expected statement, found '}'")
+	testCase("a = 1; b = 2", []Expr{write("a", 1)}, "line 1:13: expected ';' or operator, found end of the code")
+	// the lexer goes on after characters it can't read, so the errors after them are found too
+	testCase("a = 1; b = #; c = 3;", []Expr{write("a", 1), write("c", 3)},
+		"line 1:12: Unexpected character '#'")
+	testCase("a = #x; b = \"\\q\"; c = 3; d = ä + 1;", []Expr{write("c", 3)},
+		"line 1:5: Unexpected character '#'", "line 1:13: Invalid escape sequence in string", "line 1:30: Unexpected character 'ä'")
+}
+
+func TestParseError_expected(t *testing.T) {
+	testCase := func(code, expected string) {
+		t.Run(code, func(t *testing.T) {
+			_, err := ParseCode(code)
+			if err == nil || err.Error() != expected {
+				t.Errorf(`got "%v" wanted "%s"`, err, expected)
+			}
+		})
+	}
+
+	testCase("f(1 2);", `line 1:5: expected ',', ')' or operator, found integer 2`)
+	testCase("a = [1, 2;", `line 1:10: expected ',', ']' or operator, found ';'`)
+	testCase("a = b.;", `line 1:7: expected name, found ';'`)
+	testCase("a = \"x\" \"y\";", `line 1:9: expected ';' or operator, found string "y"`)
+	testCase("a = map[]Int{};", `line 1:9: expected type, found ']'`)
+	testCase("a = []{};", `line 1:7: expected ';', type or operator, found '{'`)
+	testCase("if (true) {", `line 1:12: expected '}' or statement, found end of the code`)
+	testCase("func f(a) {}", `line 1:9: expected type, found ')'`)
+	testCase("a = 1 + ;", `line 1:9: expected expression, found ';'`)
+}
+
+func TestParseFile_shebang(t *testing.T) {
+	block, err := ParseFile("test.mbs", "#!/usr/bin/env mbs\nprintln(\"hi\");")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(block.Statements) != 1 {
+		t.Fatalf("got %d statements wanted 1", len(block.Statements))
+	}
+	// the positions still match the lines of the file
+	if start := block.Statements[0].Pos().Start; start.Line != 2 || start.Column != 1 {
+		t.Errorf("got %v wanted line 2:1", start)
+	}
+
+	if _, err := ParseCode("#!/usr/bin/env mbs"); err != nil {
+		t.Error(err)
+	}
+	if _, err := ParseCode("a = 1;\n#!/usr/bin/env mbs"); err == nil {
+		t.Error("a shebang is only allowed in the first line")
+	}
+}
+
+func TestParseCode_comments(t *testing.T) {
+	block, err := ParseCode(`// line comment
+a = /* inside */ 1; // behind
+if (a == 1) {
+	/* block
+	   comment */
+	println("a // b /* c */");
+}
+b = 4 / 2 /2;
+// end`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	texts := func(comments []Comment) []string {
+		result := []string{}
+		for _, comment := range comments {
+			result = append(result, comment.Text)
+		}
+		return result
+	}
+	// every comment belongs to the innermost block
+	if diff := cmp.Diff([]string{"// line comment", "/* inside */", "// behind", "// end"}, texts(block.Comments)); diff != "" {
+		t.Error(diff)
+	}
+	body := block.Statements[1].(If).Body
+	if diff := cmp.Diff([]string{"/* block\n\t   comment */"}, texts(body.Comments)); diff != "" {
+		t.Error(diff)
+	}
+	if start := body.Comments[0].Start; start.Line != 4 || start.Column != 2 {
+		t.Errorf("got %v wanted line 4:2", start)
+	}
+	if arg := body.Statements[0].(FunctionCall).Arguments[0].(String).Data; arg != "a // b /* c */" {
+		t.Errorf(`got string "%s"`, arg)
+	}
+
+	for _, code := range []string{"a = 1; /* unterminated", "a = 1 / * 2;"} {
+		if _, err := ParseCode(code); err == nil {
+			t.Errorf(`"%s" shouldn't be parsed`, code)
+		}
+	}
+}
+
+func TestParseCode_nestedComments(t *testing.T) {
+	block, err := ParseCode(`// 1
+while (true) {
+	// 2
+	if (true) {
+		// 3
+	}
+	// 4
+	if (false) {
+		// 5
+		if (true) {}
+		// 6
+	}
+}
+// 7`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	texts := func(block Block) []string {
+		result := []string{}
+		for _, comment := range block.Comments {
+			result = append(result, comment.Text)
+		}
+		return result
+	}
+	// the comments of the inner blocks are skipped when the outer blocks claim theirs
+	body := block.Statements[0].(While).Body
+	tests := []struct {
+		block    Block
+		expected []string
+	}{
+		{*block, []string{"// 1", "// 7"}},
+		{body, []string{"// 2", "// 4"}},
+		{body.Statements[0].(If).Body, []string{"// 3"}},
+		{body.Statements[1].(If).Body, []string{"// 5", "// 6"}},
+		{body.Statements[1].(If).Body.Statements[0].(If).Body, []string{}},
+	}
+	for _, test := range tests {
+		if diff := cmp.Diff(test.expected, texts(test.block)); diff != "" {
+			t.Error(diff)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package typechecker
+
+import (
+	. "mbs/common"
+	"mbs/types"
+)
+
+// Diagnostic describes why the typechecker rejected a part of a script. Expected and Actual are only set if a value had
+// the wrong type, otherwise they are types.Nop.
+type Diagnostic struct {
+	Message  string
+	Expr     Expr // the expression which was rejected
+	Expected types.Type
+	Actual   types.Type
+	Span     Span
+	silent   bool // stops the checks of the surrounding expressions like other Diagnostics but isn't reported
+}
+
+func (d Diagnostic) Error() string {
+	return d.Span.Start.String() + ": " + d.Message
+}
+
+// newDiagnostic creates a Diagnostic which points at the whole expression.
+func newDiagnostic(expr Expr, message string) Diagnostic {
+	return newDiagnosticAt(expr, expr.Pos(), message)
+}
+
+// newDiagnosticAt creates a Diagnostic which points at a part of the expression like a single parameter of a function.
+func newDiagnosticAt(expr Expr, span Span, message string) Diagnostic {
+	return Diagnostic{Message: message, Expr: expr, Expected: types.Nop, Actual: types.Nop, Span: span}
+}
+
+// newSilentDiagnostic creates a Diagnostic for the use of a variable of the type types.Invalid. The type error of its
+// value was already reported, so the uses of the variable don't cause more errors.
+func newSilentDiagnostic(expr Expr) Diagnostic {
+	d := newDiagnostic(expr, "")
+	d.silent = true
+	return d
+}
+
+// reported removes the silent Diagnostics. It returns nil if there are no other Diagnostics.
+func reported(diagnostics []Diagnostic) []Diagnostic {
+	var result []Diagnostic
+	for _, d := range diagnostics {
+		if !d.silent {
+			result = append(result, d)
+		}
+	}
+	return result
+}
+
+// newUnexpectedType creates a Diagnostic for a value of the type actual which doesn't match the description of what was
+// expected (e.g. "a list").
+func newUnexpectedType(expr Expr, actual types.Type, expected string) Diagnostic {
+	d := newDiagnostic(expr, "Expected "+expected+" but got a value of type '"+actual.String()+"'")
+	d.Actual = actual
+	return d
+}
+
+// newTypeMismatch creates a Diagnostic for a value of the type actual where a value of the type expected was needed.
+func newTypeMismatch(expr Expr, expected, actual types.Type) Diagnostic {
+	d := newUnexpectedType(expr, actual, "a value of type '"+expected.String()+"'")
+	d.Expected = expected
+	return d
+}
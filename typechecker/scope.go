@@ -0,0 +1,39 @@
+package typechecker
+
+import "mbs/types"
+
+// scope holds the types of the variables which were declared in a block, like the scopes which are used when the code
+// is executed.
+type scope struct {
+	vars  map[string]types.Type
+	outer *scope // nil for the top level of a script and of a function body
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{vars: make(map[string]types.Type), outer: outer}
+}
+
+// lookup returns the type of the variable from the innermost scope which contains it.
+func (s *scope) lookup(name string) (types.Type, bool) {
+	if declared := s.find(name); declared != nil {
+		return declared.vars[name], true
+	}
+	return types.Nop, false
+}
+
+// find returns the innermost scope which contains the variable or nil if it wasn't declared.
+func (s *scope) find(name string) *scope {
+	for ; s != nil; s = s.outer {
+		if _, ok := s.vars[name]; ok {
+			return s
+		}
+	}
+	return nil
+}
+
+// enterScope creates a new scope inside of the current one and returns the function which leaves it again.
+func (c *Checker) enterScope() func() {
+	outer := c.variables
+	c.variables = newScope(outer)
+	return func() { c.variables = outer }
+}
@@ -2,62 +2,127 @@ package typechecker
 
 import (
 	. "mbs/common"
+	"mbs/types"
+	"strconv"
+	"strings"
 )
 
-/*This typechecker validates the type-safety of every expression in our AST*/
+/*This typechecker validates the type-safety of every expression in our AST. Every check returns the Diagnostics of
+the problems it found, so a script is valid if no Diagnostics are returned.*/
 
-// stores all the declared variables and their type to look up their type when they are used later on in the code
-var variables map[string]Type = make(map[string]Type)
+// Checker typechecks scripts. Every Checker has its own variables, functions and structs, so multiple scripts can be
+// checked at the same time.
+type Checker struct {
+	variables       *scope                  // the types of the variables that can be accessed in the current scope
+	functions       map[string]FunctionDecl // the declared functions to look up their signature when they are called
+	structs         map[string]StructDecl   // the declared structs to look up the types of their fields
+	currentFunction *FunctionDecl           // the function whose body is currently type-checked, nil outside of functions
+	loops           []string                // the labels of the loops around the current statement, "" if unlabeled
+}
 
-func TypeCheckBlock(block *Block) bool {
-	outerScopeVars := make(map[string]Type) // holds the variables declared outside of the current block
-	for k, v := range variables {
-		outerScopeVars[k] = v
+// NewChecker creates a Checker for a new script which hasn't declared any variables, functions or structs yet.
+func NewChecker() *Checker {
+	return &Checker{
+		variables: newScope(nil),
+		functions: make(map[string]FunctionDecl),
+		structs:   make(map[string]StructDecl),
 	}
+}
+
+// TypeCheckBlock checks every statement of the block and returns the Diagnostics of all of them.
+func (c *Checker) TypeCheckBlock(block *Block) []Diagnostic {
+	// the variables declared inside of the block are "deleted" after it
+	defer c.enterScope()()
+
 	// type-checking every expression inside of the current block
+	var diagnostics []Diagnostic
 	for _, expr := range block.Statements {
-		typesValid := TypeCheckExpr(expr)
-		if !typesValid {
-			return false
-		}
+		diagnostics = append(diagnostics, reported(c.TypeCheckExpr(expr))...)
 	}
-	variables = outerScopeVars // resets the variables to the ones declared outside of the  current block to "delete" the vars declared inside of it
-	return true
+	return diagnostics
 }
 
 // type-checking of expressions that can occur outside of another expression
-func TypeCheckExpr(expr Expr) bool {
+func (c *Checker) TypeCheckExpr(expr Expr) []Diagnostic {
 	switch exprType := expr.Type(); exprType {
 	case WriteVarType:
-		return TypeCheckWriteVar(expr.(WriteVar))
+		return c.TypeCheckWriteVar(expr.(WriteVar))
 	case IfType:
-		return TypeCheckIf(expr.(If))
+		return c.TypeCheckIf(expr.(If))
 	case ForType:
-		return TypeCheckFor(expr.(For))
+		return c.TypeCheckFor(expr.(For))
+	case WriteIndexType:
+		return c.TypeCheckWriteIndex(expr.(WriteIndex))
+	case WhileType:
+		return c.TypeCheckWhile(expr.(While))
+	case BreakType:
+		return c.TypeCheckLoopControl(expr, expr.(Break).Label)
+	case ContinueType:
+		return c.TypeCheckLoopControl(expr, expr.(Continue).Label)
 	case FunctionCallType:
-		valid, _ := TypeCheckFunctionCall(expr.(FunctionCall))
-		return valid
+		_, diagnostics := c.TypeCheckFunctionCall(expr.(FunctionCall))
+		return diagnostics
+	case FunctionDeclType:
+		return c.TypeCheckFunctionDecl(expr.(FunctionDecl))
+	case ReturnType:
+		return c.TypeCheckReturn(expr.(Return))
+	case StructDeclType:
+		return c.TypeCheckStructDecl(expr.(StructDecl))
+	case WriteFieldType:
+		return c.TypeCheckWriteField(expr.(WriteField))
 	}
-	return false
+	return []Diagnostic{newDiagnostic(expr, "Expected a statement")}
 }
 
-// type-checking of expressions that can occur inside of another expression
-func TypeCheckRightExpr(expr Expr) Type {
+// type-checking of expressions that can occur inside of another expression. The returned type is only types.Nop if
+// there are Diagnostics.
+func (c *Checker) TypeCheckRightExpr(expr Expr) (types.Type, []Diagnostic) {
 	switch exprType := expr.Type(); exprType {
 	case OperatorType:
-		return TypeCheckOperator(expr.(Operator))
+		return c.TypeCheckOperator(expr.(Operator))
+	case UnaryOperatorType:
+		return c.TypeCheckUnaryOperator(expr.(UnaryOperator))
 	case FunctionCallType:
-		valid, returnType := TypeCheckFunctionCall(expr.(FunctionCall))
-		if !valid {
-			return NopType
+		function := expr.(FunctionCall)
+		returnType, diagnostics := c.TypeCheckFunctionCall(function)
+		if diagnostics == nil && returnType == types.Nop {
+			return types.Nop, []Diagnostic{newDiagnostic(expr, "The function '"+function.Name+"' doesn't return a value")}
 		}
-		return returnType
+		return returnType, diagnostics
 	case ReadVarType:
-		return TypeCheckReadVar(expr.(ReadVar))
-	case IntegerType, FloatType, BooleanType, StringType:
-		return exprType
+		return c.TypeCheckReadVar(expr.(ReadVar))
+	case ListType:
+		return c.TypeCheckList(expr.(List))
+	case IndexType:
+		return c.TypeCheckIndex(expr.(Index))
+	case MapType:
+		return c.TypeCheckMap(expr.(Map))
+	case StructType:
+		return c.TypeCheckStruct(expr.(Struct))
+	case FieldType:
+		return c.TypeCheckField(expr.(Field))
+	case BooleanType:
+		return types.Boolean, nil
+	case IntegerType:
+		return types.Int, nil
+	case FloatType:
+		return types.Float, nil
+	case StringType:
+		return types.String, nil
+	}
+	return types.Nop, []Diagnostic{newDiagnostic(expr, "Expected an expression which has a value")}
+}
+
+// expectType checks that the expression has a value of the expected type.
+func (c *Checker) expectType(expr Expr, expected types.Type) []Diagnostic {
+	tipe, diagnostics := c.TypeCheckRightExpr(expr)
+	if diagnostics != nil {
+		return diagnostics
 	}
-	return NopType
+	if tipe != expected {
+		return []Diagnostic{newTypeMismatch(expr, expected, tipe)}
+	}
+	return nil
 }
 
 var (
@@ -67,124 +132,535 @@ var (
 	arithmOps        = []string{"+", "-", "*", "/"}
 )
 
-func TypeCheckOperator(operator Operator) Type {
+func (c *Checker) TypeCheckOperator(operator Operator) (types.Type, []Diagnostic) {
 	// checking the type of the expressions left and right of our operator
-	firstExpType := TypeCheckRightExpr(operator.FirstExp)
-	secondExpType := TypeCheckRightExpr(operator.SecondExp)
+	firstExpType, firstDiagnostics := c.TypeCheckRightExpr(operator.FirstExp)
+	secondExpType, secondDiagnostics := c.TypeCheckRightExpr(operator.SecondExp)
+	if diagnostics := append(firstDiagnostics, secondDiagnostics...); diagnostics != nil {
+		return types.Nop, diagnostics
+	}
 
 	// checking if the types can be used with the given operator
 	for _, symbol := range typeEqualCompOps {
 		if symbol == operator.Symbol && firstExpType == secondExpType {
-			return BooleanType
+			return types.Boolean, nil
 		}
 	}
 
 	for _, symbol := range boolCompOps {
-		if symbol == operator.Symbol && firstExpType == BooleanType && secondExpType == BooleanType {
-			return BooleanType
+		if symbol == operator.Symbol && firstExpType == types.Boolean && secondExpType == types.Boolean {
+			return types.Boolean, nil
 		}
 	}
 
 	for _, symbol := range arithmCompOps {
-		if symbol == operator.Symbol && (firstExpType == IntegerType || firstExpType == FloatType) && (secondExpType == IntegerType || secondExpType == FloatType) {
-			return BooleanType
+		if symbol == operator.Symbol && (firstExpType == types.Int || firstExpType == types.Float) && (secondExpType == types.Int || secondExpType == types.Float) {
+			return types.Boolean, nil
 		}
 	}
 
 	for _, symbol := range arithmOps {
 		if symbol == operator.Symbol {
-			if firstExpType == FloatType && (secondExpType == FloatType || secondExpType == IntegerType) {
-				return FloatType
+			if firstExpType == types.Float && (secondExpType == types.Float || secondExpType == types.Int) {
+				return types.Float, nil
 			}
-			if secondExpType == FloatType && (firstExpType == FloatType || firstExpType == IntegerType) {
-				return FloatType
+			if secondExpType == types.Float && (firstExpType == types.Float || firstExpType == types.Int) {
+				return types.Float, nil
 			}
-			if firstExpType == IntegerType && secondExpType == IntegerType {
-				return IntegerType
+			if firstExpType == types.Int && secondExpType == types.Int {
+				return types.Int, nil
 			}
 		}
 	}
 
-	if operator.Symbol == "+" && firstExpType == StringType && secondExpType == StringType {
-		return StringType
+	if operator.Symbol == "+" && firstExpType == types.String && secondExpType == types.String {
+		return types.String, nil
 	}
-	return NopType
+	return types.Nop, []Diagnostic{newDiagnostic(operator, "The operator '"+operator.Symbol+"' can't be used with the types '"+
+		firstExpType.String()+"' and '"+secondExpType.String()+"'")}
 }
 
-// returns if the types in this function call are valid and what type is returned by the function
-func TypeCheckFunctionCall(function FunctionCall) (bool, Type) {
-	if function.Name == "println" && TypeCheckRightExpr(function.Argument) == StringType {
-		return true, NopType
-	} else if function.Name == "readln" && function.Argument.Type() == NopType {
-		return true, StringType
+func (c *Checker) TypeCheckUnaryOperator(operator UnaryOperator) (types.Type, []Diagnostic) {
+	expType, diagnostics := c.TypeCheckRightExpr(operator.Exp)
+	if diagnostics != nil {
+		return types.Nop, diagnostics
+	}
+
+	if operator.Symbol == "!" && expType == types.Boolean {
+		return types.Boolean, nil
+	}
+	if operator.Symbol == "-" && (expType == types.Int || expType == types.Float) {
+		return expType, nil
 	}
-	return false, NopType
+	return types.Nop, []Diagnostic{newDiagnostic(operator, "The operator '"+operator.Symbol+"' can't be used with the type '"+expType.String()+"'")}
 }
 
-func TypeCheckWriteVar(writeVar WriteVar) bool {
-	exprType := TypeCheckRightExpr(writeVar.Expr)
-	if exprType == NopType {
-		return false
+// returns what type is returned by the function and the Diagnostics of the arguments. The type is types.Nop if the
+// function doesn't return a value.
+func (c *Checker) TypeCheckFunctionCall(function FunctionCall) (types.Type, []Diagnostic) {
+	args := function.Arguments
+	if builtin, ok := LookupBuiltin(function.Name); ok {
+		return c.typeCheckBuiltinCall(function, builtin)
+	}
+
+	decl, ok := c.functions[function.Name]
+	if !ok {
+		return types.Nop, []Diagnostic{newDiagnostic(function, "Unknown function '"+function.Name+"'")}
+	}
+	if len(args) != len(decl.Params) {
+		return types.Nop, wrongArgumentCount(function, len(decl.Params))
+	}
+	var diagnostics []Diagnostic
+	for i, param := range decl.Params {
+		diagnostics = append(diagnostics, c.expectType(args[i], param.Type)...)
+	}
+	if diagnostics != nil {
+		return types.Nop, diagnostics
 	}
-	variables[writeVar.Name] = exprType
-	return true
+	return decl.Returns, nil
 }
 
-func TypeCheckIf(ifExpr If) bool {
-	if !TypeCheckCondition(ifExpr.Condition) {
-		return false
+// wrongArgumentCount returns the Diagnostic for a function call which doesn't have the expected number of arguments.
+func wrongArgumentCount(function FunctionCall, expected int) []Diagnostic {
+	return []Diagnostic{newDiagnostic(function, "The function '"+function.Name+"' expects "+strconv.Itoa(expected)+
+		" arguments but got "+strconv.Itoa(len(function.Arguments)))}
+}
+
+// typeCheckBuiltinCall checks the arguments of a call of a builtin function and returns what type is returned.
+func (c *Checker) typeCheckBuiltinCall(function FunctionCall, builtin Builtin) (types.Type, []Diagnostic) {
+	args := function.Arguments
+	if len(args) != len(builtin.Params) {
+		return types.Nop, wrongArgumentCount(function, len(builtin.Params))
+	}
+
+	argTypes := make([]types.Type, len(args))
+	var diagnostics []Diagnostic
+	for i, param := range builtin.Params {
+		argType, argDiagnostics := c.TypeCheckRightExpr(args[i])
+		if argDiagnostics == nil && param != types.Nop && argType != param {
+			argDiagnostics = []Diagnostic{newTypeMismatch(args[i], param, argType)}
+		}
+		argTypes[i] = argType
+		diagnostics = append(diagnostics, argDiagnostics...)
+	}
+	if diagnostics != nil {
+		return types.Nop, diagnostics
+	}
+	if builtin.Check == nil {
+		return builtin.Returns, nil
 	}
 
-	return TypeCheckBlock(&ifExpr.Body)
+	returns, err := builtin.Check(argTypes)
+	if err == nil {
+		return returns, nil
+	}
+	arg := args[err.Index]
+	if err.Expected != types.Nop {
+		return types.Nop, []Diagnostic{newTypeMismatch(arg, err.Expected, argTypes[err.Index])}
+	}
+	return types.Nop, []Diagnostic{newUnexpectedType(arg, argTypes[err.Index], err.Description)}
 }
 
-func TypeCheckFor(forExpr For) bool {
-	initType := forExpr.Init.Type()
-	if initType == WriteVarType {
-		if !TypeCheckWriteVar(forExpr.Init.(WriteVar)) {
-			return false
+func (c *Checker) TypeCheckFunctionDecl(decl FunctionDecl) []Diagnostic {
+	// functions can't be declared inside of other functions or be declared twice
+	if c.currentFunction != nil {
+		return []Diagnostic{newDiagnostic(decl, "Functions can't be declared inside of other functions")}
+	}
+	if _, ok := c.functions[decl.Name]; ok {
+		return []Diagnostic{newDiagnostic(decl, "The function '"+decl.Name+"' is already declared")}
+	}
+	if _, ok := LookupBuiltin(decl.Name); ok {
+		return []Diagnostic{newDiagnostic(decl, "The function '"+decl.Name+"' is a built in function")}
+	}
+
+	var diagnostics []Diagnostic
+	if message := c.typeError(decl.Returns); message != "" {
+		diagnostics = append(diagnostics, newDiagnostic(decl, message))
+	}
+
+	// the body only has access to the parameters of the function
+	params := newScope(nil)
+	for _, param := range decl.Params {
+		if _, ok := params.vars[param.Name]; ok {
+			diagnostics = append(diagnostics, newDiagnosticAt(decl, param.Span, "The parameter '"+param.Name+"' is declared twice"))
 		}
-	} else if initType != NopType {
-		return false
+		if message := c.typeError(param.Type); message != "" {
+			diagnostics = append(diagnostics, newDiagnosticAt(decl, param.Span, message))
+		}
+		params.vars[param.Name] = param.Type
 	}
 
-	if !TypeCheckCondition(forExpr.Condition) {
-		if forExpr.Condition.Type() != NopType {
-			return false
+	// the function is registered before checking the body so that it can call itself
+	c.functions[decl.Name] = decl
+
+	// break and continue can't leave the function
+	outerScopeVars, outerLoops := c.variables, c.loops
+	c.variables, c.loops = params, nil
+	c.currentFunction = &decl
+	diagnostics = append(diagnostics, c.TypeCheckBlock(&decl.Body)...)
+	c.variables, c.loops = outerScopeVars, outerLoops
+	c.currentFunction = nil
+
+	// a function with a return type has to return a value on every path through its body
+	if decl.Returns != types.Nop && !blockReturns(decl.Body) {
+		diagnostics = append(diagnostics, newDiagnostic(decl, "The function '"+decl.Name+"' doesn't return a value on every path"))
+	}
+	return diagnostics
+}
+
+func (c *Checker) TypeCheckReturn(ret Return) []Diagnostic {
+	if c.currentFunction == nil {
+		return []Diagnostic{newDiagnostic(ret, "return can only be used inside of a function")}
+	}
+
+	if ret.Expr.Type() == NopType {
+		if c.currentFunction.Returns != types.Nop {
+			d := newDiagnostic(ret, "The function '"+c.currentFunction.Name+"' has to return a value")
+			d.Expected = c.currentFunction.Returns
+			return []Diagnostic{d}
 		}
+		return nil
+	}
+	if c.currentFunction.Returns == types.Nop {
+		return []Diagnostic{newDiagnostic(ret, "The function '"+c.currentFunction.Name+"' doesn't return a value")}
 	}
+	return c.expectType(ret.Expr, c.currentFunction.Returns)
+}
 
-	advType := forExpr.Advancement.Type()
-	if advType == WriteVarType {
-		if !TypeCheckWriteVar(forExpr.Advancement.(WriteVar)) {
-			return false
+// blockReturns checks if executing the block always ends in a return statement.
+func blockReturns(block Block) bool {
+	for _, stmt := range block.Statements {
+		switch stmt.Type() {
+		case ReturnType:
+			return true
+		case IfType:
+			// an if only returns for sure if both of its branches return
+			ifExpr := stmt.(If)
+			if ifExpr.Else != nil && blockReturns(ifExpr.Body) && blockReturns(*ifExpr.Else) {
+				return true
+			}
 		}
-	} else if advType != NopType {
-		return false
 	}
+	return false
+}
 
-	return TypeCheckBlock(&forExpr.Body)
+func (c *Checker) TypeCheckWriteVar(writeVar WriteVar) []Diagnostic {
+	exprType, diagnostics := c.TypeCheckRightExpr(writeVar.Expr)
+	if diagnostics != nil {
+		// a new variable is still declared, otherwise every use of it would be reported as unknown
+		if c.variables.find(writeVar.Name) == nil {
+			c.variables.vars[writeVar.Name] = types.Invalid
+		}
+		return diagnostics
+	}
+
+	// variables of outer scopes keep their type since the new value is still visible after the current block
+	declared := c.variables.find(writeVar.Name)
+	if declared == nil {
+		declared = c.variables
+	} else if declared != c.variables && declared.vars[writeVar.Name] != exprType {
+		if declared.vars[writeVar.Name] == types.Invalid {
+			// the type error of the variable was already reported
+			return nil
+		}
+		return []Diagnostic{newTypeMismatch(writeVar.Expr, declared.vars[writeVar.Name], exprType)}
+	}
+	declared.vars[writeVar.Name] = exprType
+	return nil
 }
 
-func TypeCheckCondition(expr Expr) bool {
-	switch exprType := expr.Type(); exprType {
-	case BooleanType:
-		return true
-	case OperatorType:
-		return TypeCheckOperator(expr.(Operator)) == BooleanType
-	case ReadVarType:
-		return TypeCheckReadVar(expr.(ReadVar)) == BooleanType
-	case FunctionCallType:
-		valid, returnType := TypeCheckFunctionCall(expr.(FunctionCall))
-		return valid && returnType == BooleanType
+func (c *Checker) TypeCheckIf(ifExpr If) []Diagnostic {
+	diagnostics := c.TypeCheckCondition(ifExpr.Condition)
+	diagnostics = append(diagnostics, c.TypeCheckBlock(&ifExpr.Body)...)
+
+	// the else branch gets its own scope, so variables declared in the body aren't visible in it
+	if ifExpr.Else != nil {
+		diagnostics = append(diagnostics, c.TypeCheckBlock(ifExpr.Else)...)
 	}
+	return diagnostics
+}
 
-	return false
+func (c *Checker) TypeCheckFor(forExpr For) []Diagnostic {
+	// the variable declared in the initialization only exists inside of the loop
+	defer c.enterScope()()
+
+	var diagnostics []Diagnostic
+	diagnostics = append(diagnostics, c.typeCheckForPart(forExpr.Init)...)
+	if forExpr.Condition.Type() != NopType {
+		diagnostics = append(diagnostics, c.TypeCheckCondition(forExpr.Condition)...)
+	}
+	diagnostics = append(diagnostics, c.typeCheckAdvancement(forExpr.Advancement)...)
+
+	return append(diagnostics, c.typeCheckLoopBody(forExpr, forExpr.Label, &forExpr.Body)...)
+}
+
+// typeCheckForPart checks the initialization or advancement of a for loop which is either empty or an assignment.
+func (c *Checker) typeCheckForPart(expr Expr) []Diagnostic {
+	switch expr.Type() {
+	case WriteVarType:
+		return c.TypeCheckWriteVar(expr.(WriteVar))
+	case NopType:
+		return nil
+	}
+	return []Diagnostic{newDiagnostic(expr, "Expected an assignment")}
+}
+
+// typeCheckAdvancement checks the advancement of a for loop. It runs before the condition is checked again, so it can't
+// give a variable a new type even though it belongs to the same scope as the initialization.
+func (c *Checker) typeCheckAdvancement(expr Expr) []Diagnostic {
+	writeVar, ok := expr.(WriteVar)
+	if !ok {
+		return c.typeCheckForPart(expr)
+	}
+	declared, ok := c.variables.lookup(writeVar.Name)
+	if !ok || declared == types.Invalid {
+		return c.TypeCheckWriteVar(writeVar)
+	}
+	return c.expectType(writeVar.Expr, declared)
+}
+
+func (c *Checker) TypeCheckWhile(while While) []Diagnostic {
+	diagnostics := c.TypeCheckCondition(while.Condition)
+
+	return append(diagnostics, c.typeCheckLoopBody(while, while.Label, &while.Body)...)
+}
+
+// typeCheckLoopBody checks the body of a loop. break and continue are only allowed inside of it.
+func (c *Checker) typeCheckLoopBody(loop Expr, label string, body *Block) []Diagnostic {
+	var diagnostics []Diagnostic
+	if label != "" {
+		for _, l := range c.loops {
+			if l == label {
+				diagnostics = append(diagnostics, newDiagnostic(loop, "The label '"+label+"' is already used by an outer loop"))
+			}
+		}
+	}
+
+	c.loops = append(c.loops, label)
+	diagnostics = append(diagnostics, c.TypeCheckBlock(body)...)
+	c.loops = c.loops[:len(c.loops)-1]
+	return diagnostics
+}
+
+// TypeCheckLoopControl checks that a break or continue is inside of a loop which has the given label (if any).
+func (c *Checker) TypeCheckLoopControl(expr Expr, label string) []Diagnostic {
+	for _, l := range c.loops {
+		if label == "" || l == label {
+			return nil
+		}
+	}
+
+	if label == "" {
+		return []Diagnostic{newDiagnostic(expr, strings.ToLower(string(expr.Type()))+" can only be used inside of a loop")}
+	}
+	return []Diagnostic{newDiagnostic(expr, "There is no loop with the label '"+label+"' around this statement")}
 }
 
-func TypeCheckReadVar(readVar ReadVar) Type {
-	if tipe, ok := variables[readVar.Name]; ok {
-		return tipe
+func (c *Checker) TypeCheckCondition(expr Expr) []Diagnostic {
+	return c.expectType(expr, types.Boolean)
+}
+
+// TypeCheckList returns the type of a list literal. All elements must have the same type. Empty lists need an explicit
+// type for their elements.
+func (c *Checker) TypeCheckList(list List) (types.Type, []Diagnostic) {
+	if message := c.typeError(list.ElemType); message != "" {
+		return types.Nop, []Diagnostic{newDiagnostic(list, message)}
+	}
+
+	var diagnostics []Diagnostic
+	elemType := list.ElemType
+	for _, elem := range list.Elems {
+		tipe, elemDiagnostics := c.TypeCheckRightExpr(elem)
+		if elemDiagnostics != nil {
+			diagnostics = append(diagnostics, elemDiagnostics...)
+			continue
+		}
+		if elemType == types.Nop {
+			elemType = tipe
+		}
+		if tipe != elemType {
+			diagnostics = append(diagnostics, newTypeMismatch(elem, elemType, tipe))
+		}
+	}
+
+	if diagnostics != nil {
+		return types.Nop, diagnostics
+	}
+	if elemType == types.Nop {
+		return types.Nop, []Diagnostic{newDiagnostic(list, "The type of the elements of an empty list has to be written like []Int{}")}
+	}
+	return types.List{Elem: elemType}, nil
+}
+
+// TypeCheckIndex returns the type of the element which is read from a list or map.
+func (c *Checker) TypeCheckIndex(index Index) (types.Type, []Diagnostic) {
+	containerType, diagnostics := c.TypeCheckRightExpr(index.Exp)
+	if diagnostics != nil {
+		return types.Nop, diagnostics
+	}
+
+	switch container := containerType.(type) {
+	case types.List:
+		if diagnostics := c.expectType(index.Index, types.Int); diagnostics != nil {
+			return types.Nop, diagnostics
+		}
+		return container.Elem, nil
+	case types.Map:
+		if diagnostics := c.expectType(index.Index, container.Key); diagnostics != nil {
+			return types.Nop, diagnostics
+		}
+		return container.Value, nil
+	}
+	return types.Nop, []Diagnostic{newUnexpectedType(index.Exp, containerType, "a list or a map")}
+}
+
+// TypeCheckMap returns the type of a map literal. All keys and values must have the types which were written.
+func (c *Checker) TypeCheckMap(m Map) (types.Type, []Diagnostic) {
+	mapType := types.Map{Key: m.KeyType, Value: m.ValueType}
+	if message := c.typeError(mapType); message != "" {
+		return types.Nop, []Diagnostic{newDiagnostic(m, message)}
+	}
+
+	var diagnostics []Diagnostic
+	for _, entry := range m.Entries {
+		diagnostics = append(diagnostics, c.expectType(entry.Key, m.KeyType)...)
+		diagnostics = append(diagnostics, c.expectType(entry.Value, m.ValueType)...)
+	}
+	if diagnostics != nil {
+		return types.Nop, diagnostics
+	}
+	return mapType, nil
+}
+
+func (c *Checker) TypeCheckStructDecl(decl StructDecl) []Diagnostic {
+	// structs can't be declared twice or have the name of a built in type
+	if _, ok := c.structs[decl.Name]; ok {
+		return []Diagnostic{newDiagnostic(decl, "The struct '"+decl.Name+"' is already declared")}
+	}
+	if _, ok := types.Lookup(decl.Name); ok {
+		return []Diagnostic{newDiagnostic(decl, "The struct '"+decl.Name+"' has the name of a built in type")}
+	}
+
+	// the struct is registered before checking the fields so that it can contain lists or maps of itself
+	c.structs[decl.Name] = decl
+
+	var diagnostics []Diagnostic
+	fields := make(map[string]bool)
+	for _, field := range decl.Fields {
+		if fields[field.Name] {
+			diagnostics = append(diagnostics, newDiagnosticAt(decl, field.Span, "The field '"+field.Name+"' is declared twice"))
+		}
+		if message := c.typeError(field.Type); message != "" {
+			diagnostics = append(diagnostics, newDiagnosticAt(decl, field.Span, message))
+		}
+		fields[field.Name] = true
+	}
+	return diagnostics
+}
+
+// TypeCheckStruct returns the type of a struct literal. Every field has to be given a value of the right type exactly
+// once.
+func (c *Checker) TypeCheckStruct(s Struct) (types.Type, []Diagnostic) {
+	decl, ok := c.structs[s.Name]
+	if !ok {
+		return types.Nop, []Diagnostic{newDiagnostic(s, "Unknown struct '"+s.Name+"'")}
+	}
+
+	var diagnostics []Diagnostic
+	given := make(map[string]bool)
+	for _, field := range s.Fields {
+		fieldType, ok := fieldType(decl, field.Name)
+		if !ok {
+			diagnostics = append(diagnostics, newDiagnosticAt(s, field.Span, "The struct '"+s.Name+"' has no field '"+field.Name+"'"))
+		} else if given[field.Name] {
+			diagnostics = append(diagnostics, newDiagnosticAt(s, field.Span, "The field '"+field.Name+"' is given twice"))
+		} else {
+			diagnostics = append(diagnostics, c.expectType(field.Value, fieldType)...)
+		}
+		given[field.Name] = true
+	}
+	for _, field := range decl.Fields {
+		if !given[field.Name] {
+			diagnostics = append(diagnostics, newDiagnostic(s, "The field '"+field.Name+"' is missing"))
+		}
+	}
+
+	if diagnostics != nil {
+		return types.Nop, diagnostics
+	}
+	return types.Struct{Name: s.Name}, nil
+}
+
+// TypeCheckField returns the type of the field which is read from a struct.
+func (c *Checker) TypeCheckField(field Field) (types.Type, []Diagnostic) {
+	structType, diagnostics := c.TypeCheckRightExpr(field.Exp)
+	if diagnostics != nil {
+		return types.Nop, diagnostics
+	}
+	s, ok := structType.(types.Struct)
+	if !ok {
+		return types.Nop, []Diagnostic{newUnexpectedType(field.Exp, structType, "a struct")}
+	}
+
+	tipe, ok := fieldType(c.structs[s.Name], field.Name)
+	if !ok {
+		return types.Nop, []Diagnostic{newDiagnostic(field, "The struct '"+s.Name+"' has no field '"+field.Name+"'")}
+	}
+	return tipe, nil
+}
+
+func (c *Checker) TypeCheckWriteField(writeField WriteField) []Diagnostic {
+	tipe, diagnostics := c.TypeCheckField(Field{Span: writeField.Span, Exp: writeField.Exp, Name: writeField.Name})
+	if diagnostics != nil {
+		return diagnostics
+	}
+	return c.expectType(writeField.Value, tipe)
+}
+
+// fieldType looks up the type of the field with the given name.
+func fieldType(decl StructDecl, name string) (types.Type, bool) {
+	for _, field := range decl.Fields {
+		if field.Name == name {
+			return field.Type, true
+		}
+	}
+	return types.Nop, false
+}
+
+// typeError checks that all structs inside of the type were declared and that the keys of all maps inside of the type
+// are Booleans, Strings, Ints or Floats. It returns the message of the problem or "" if the type is valid.
+func (c *Checker) typeError(t types.Type) string {
+	switch t := t.(type) {
+	case types.Struct:
+		if _, ok := c.structs[t.Name]; !ok {
+			return "Unknown struct '" + t.Name + "'"
+		}
+	case types.List:
+		return c.typeError(t.Elem)
+	case types.Map:
+		switch t.Key {
+		case types.Boolean, types.String, types.Int, types.Float:
+			return c.typeError(t.Value)
+		}
+		return "The keys of a map can't be of the type '" + t.Key.String() + "'"
+	}
+	return ""
+}
+
+func (c *Checker) TypeCheckWriteIndex(writeIndex WriteIndex) []Diagnostic {
+	elemType, diagnostics := c.TypeCheckIndex(Index{Span: writeIndex.Span, Exp: writeIndex.Exp, Index: writeIndex.Index})
+	if diagnostics != nil {
+		return diagnostics
+	}
+	return c.expectType(writeIndex.Value, elemType)
+}
+
+func (c *Checker) TypeCheckReadVar(readVar ReadVar) (types.Type, []Diagnostic) {
+	if tipe, ok := c.variables.lookup(readVar.Name); ok {
+		if tipe == types.Invalid {
+			return types.Nop, []Diagnostic{newSilentDiagnostic(readVar)}
+		}
+		return tipe, nil
 	}
-	return NopType
+	return types.Nop, []Diagnostic{newDiagnostic(readVar, "Unknown variable '"+readVar.Name+"'")}
 }
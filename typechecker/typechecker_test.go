@@ -1,100 +1,620 @@
 package typechecker
 
 import (
+	"io"
 	. "mbs/common"
+	. "mbs/parser"
+	"mbs/types"
+	"strings"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestTypeCheckExpr(t *testing.T) {
-	testTypeCheckExpr(t, WriteVar{Name: "abc", Expr: FunctionCall{Name: "readln", Argument: Nop{}}})
-	testTypeCheckExpr(t, If{
+	c := NewChecker()
+	testTypeCheckExpr(t, c, WriteVar{Name: "abc", Expr: FunctionCall{Name: "readln", Arguments: []Expr{}}})
+	testTypeCheckExpr(t, c, If{
 		Condition: Operator{Symbol: "==", FirstExp: ReadVar{Name: "abc"}, SecondExp: String{Data: "abc"}},
 		Body: Block{Statements: []Expr{
-			FunctionCall{Name: "println", Argument: String{Data: "Equal!"}},
+			FunctionCall{Name: "println", Arguments: []Expr{String{Data: "Equal!"}}},
 			WriteVar{Name: "def", Expr: Integer{Data: 5}},
 			For{
 				Init:        WriteVar{Name: "i", Expr: Integer{Data: 0}},
 				Condition:   Operator{Symbol: "<", FirstExp: ReadVar{Name: "i"}, SecondExp: ReadVar{Name: "def"}},
 				Advancement: WriteVar{Name: "i", Expr: Operator{Symbol: "+", FirstExp: ReadVar{Name: "i"}, SecondExp: Integer{Data: 1}}},
 				Body: Block{Statements: []Expr{
-					FunctionCall{Name: "println", Argument: String{Data: "iterating"}},
+					FunctionCall{Name: "println", Arguments: []Expr{String{Data: "iterating"}}},
 				}},
 			}}},
 	})
 }
 
-func testTypeCheckExpr(t *testing.T, expr Expr) {
-	typesValid := TypeCheckExpr(expr)
+func testTypeCheckExpr(t *testing.T, c *Checker, expr Expr) {
+	diagnostics := c.TypeCheckExpr(expr)
 
-	if !typesValid {
-		t.Errorf(`Types are invalid at expression: "%+v" but should be valid`, expr)
+	if len(diagnostics) != 0 {
+		t.Errorf(`Types are invalid at expression: "%+v" but should be valid: %v`, expr, diagnostics)
 	}
 }
 
-func testTypeCheckExprNegative(t *testing.T, expr Expr) {
-	typesValid := TypeCheckExpr(expr)
+func testTypeCheckExprNegative(t *testing.T, c *Checker, expr Expr) {
+	diagnostics := c.TypeCheckExpr(expr)
 
-	if typesValid {
+	if len(diagnostics) == 0 {
 		t.Errorf(`Types are valid at expression: "%+v" but should be invalid`, expr)
 	}
 }
 
 func TestTypeCheckOperator(t *testing.T) {
-	testTypeCheckOperator(t, Operator{Symbol: "==", FirstExp: Integer{Data: 1}, SecondExp: Integer{Data: 1}}, BooleanType)
-	testTypeCheckOperator(t, Operator{Symbol: ">=", FirstExp: Integer{Data: 1}, SecondExp: Integer{Data: 1}}, BooleanType)
-	testTypeCheckOperator(t, Operator{Symbol: "*", FirstExp: Integer{Data: 1}, SecondExp: Integer{Data: 1}}, IntegerType)
-	testTypeCheckOperator(t, Operator{Symbol: "+", FirstExp: Float{Data: 1.0}, SecondExp: Integer{Data: 1}}, FloatType)
-	testTypeCheckOperator(t, Operator{Symbol: "+", FirstExp: String{Data: "ab"}, SecondExp: String{Data: "cd"}}, StringType)
-	testTypeCheckOperator(t, Operator{Symbol: "||", FirstExp: Boolean{Data: true}, SecondExp: Boolean{Data: true}}, BooleanType)
-	testTypeCheckOperator(t, Operator{
+	c := NewChecker()
+	testTypeCheckOperator(t, c, Operator{Symbol: "==", FirstExp: Integer{Data: 1}, SecondExp: Integer{Data: 1}}, types.Boolean)
+	testTypeCheckOperator(t, c, Operator{Symbol: ">=", FirstExp: Integer{Data: 1}, SecondExp: Integer{Data: 1}}, types.Boolean)
+	testTypeCheckOperator(t, c, Operator{Symbol: "*", FirstExp: Integer{Data: 1}, SecondExp: Integer{Data: 1}}, types.Int)
+	testTypeCheckOperator(t, c, Operator{Symbol: "+", FirstExp: Float{Data: 1.0}, SecondExp: Integer{Data: 1}}, types.Float)
+	testTypeCheckOperator(t, c, Operator{Symbol: "+", FirstExp: String{Data: "ab"}, SecondExp: String{Data: "cd"}}, types.String)
+	testTypeCheckOperator(t, c, Operator{Symbol: "||", FirstExp: Boolean{Data: true}, SecondExp: Boolean{Data: true}}, types.Boolean)
+	testTypeCheckOperator(t, c, Operator{
 		Symbol:    "==",
 		FirstExp:  Integer{Data: 1},
-		SecondExp: Operator{Symbol: "-", FirstExp: Integer{Data: 2}, SecondExp: Integer{Data: 1}}}, BooleanType)
+		SecondExp: Operator{Symbol: "-", FirstExp: Integer{Data: 2}, SecondExp: Integer{Data: 1}}}, types.Boolean)
 
-	testTypeCheckOperatorNegative(t, Operator{Symbol: "<=", FirstExp: Boolean{Data: false}, SecondExp: Boolean{Data: true}})
-	testTypeCheckOperatorNegative(t, Operator{Symbol: "!=", FirstExp: Float{Data: 1.0}, SecondExp: Integer{Data: 1}})
-	testTypeCheckOperatorNegative(t, Operator{Symbol: "&&", FirstExp: Integer{Data: 1}, SecondExp: Integer{Data: 1}})
-	testTypeCheckOperatorNegative(t, Operator{Symbol: "+", FirstExp: String{Data: "1"}, SecondExp: Integer{Data: 1}})
-	testTypeCheckOperatorNegative(t, Operator{Symbol: "-", FirstExp: Boolean{Data: false}, SecondExp: Boolean{Data: true}})
+	testTypeCheckOperatorNegative(t, c, Operator{Symbol: "<=", FirstExp: Boolean{Data: false}, SecondExp: Boolean{Data: true}})
+	testTypeCheckOperatorNegative(t, c, Operator{Symbol: "!=", FirstExp: Float{Data: 1.0}, SecondExp: Integer{Data: 1}})
+	testTypeCheckOperatorNegative(t, c, Operator{Symbol: "&&", FirstExp: Integer{Data: 1}, SecondExp: Integer{Data: 1}})
+	testTypeCheckOperatorNegative(t, c, Operator{Symbol: "+", FirstExp: String{Data: "1"}, SecondExp: Integer{Data: 1}})
+	testTypeCheckOperatorNegative(t, c, Operator{Symbol: "-", FirstExp: Boolean{Data: false}, SecondExp: Boolean{Data: true}})
 }
 
-func testTypeCheckOperator(t *testing.T, operator Operator, expectedType Type) {
-	tipe := TypeCheckOperator(operator)
+func testTypeCheckOperator(t *testing.T, c *Checker, operator Operator, expectedType types.Type) {
+	tipe, _ := c.TypeCheckOperator(operator)
 
 	if tipe != expectedType {
 		t.Errorf(`expected type "%v" but got type "%v" after input of "%+v"`, expectedType, tipe, operator)
 	}
 }
 
-func testTypeCheckOperatorNegative(t *testing.T, operator Operator) {
-	tipe := TypeCheckOperator(operator)
+func testTypeCheckOperatorNegative(t *testing.T, c *Checker, operator Operator) {
+	tipe, diagnostics := c.TypeCheckOperator(operator)
 
-	if tipe != NopType {
+	if tipe != types.Nop || len(diagnostics) == 0 {
 		t.Errorf(`expected type Nop but got type "%v" after input of "%+v"`, tipe, operator)
 	}
 }
 
+func TestTypeCheckOperator_eval(t *testing.T) {
+	c := NewChecker()
+	// one operand of every primitive type, none of them is zero so that "/" never fails
+	operands := []Expr{Integer{Data: 7}, Float{Data: 2.5}, String{Data: "ab"}, Boolean{Data: true}}
+	symbols := append(append(append(append([]string{}, typeEqualCompOps...), boolCompOps...), arithmCompOps...), arithmOps...)
+	interp := NewInterpreter(strings.NewReader(""), io.Discard)
+
+	// every combination the typechecker accepts has to evaluate to a value of the predicted type
+	for _, symbol := range symbols {
+		for _, first := range operands {
+			for _, second := range operands {
+				operator := Operator{Symbol: symbol, FirstExp: first, SecondExp: second}
+				expectedType, diagnostics := c.TypeCheckOperator(operator)
+				if diagnostics != nil {
+					continue
+				}
+
+				if valueType := typeOfValue(operator.Eval(interp)); valueType != expectedType {
+					t.Errorf(`"%s" evaluated to a value of type %s but the typechecker expected %s`,
+						operator.Print(), valueType.String(), expectedType.String())
+				}
+			}
+		}
+	}
+
+	// the values are promoted the same way
+	testCases := []struct {
+		operator Operator
+		expected interface{}
+	}{
+		{Operator{Symbol: "+", FirstExp: Integer{Data: 1}, SecondExp: Float{Data: 2.5}}, 3.5},
+		{Operator{Symbol: "/", FirstExp: Float{Data: 1}, SecondExp: Integer{Data: 4}}, 0.25},
+		{Operator{Symbol: "/", FirstExp: Integer{Data: 7}, SecondExp: Integer{Data: 2}}, int64(3)},
+		{Operator{Symbol: "<", FirstExp: Integer{Data: 2}, SecondExp: Float{Data: 2.5}}, true},
+		{Operator{Symbol: "||", FirstExp: Boolean{Data: false}, SecondExp: Boolean{Data: true}}, true},
+		{Operator{Symbol: "&&", FirstExp: Boolean{Data: false}, SecondExp: Boolean{Data: true}}, false},
+	}
+	for _, testCase := range testCases {
+		if result := testCase.operator.Eval(interp); result != testCase.expected {
+			t.Errorf(`"%s" evaluated to %v wanted %v`, testCase.operator.Print(), result, testCase.expected)
+		}
+	}
+}
+
+// typeOfValue returns the Type of a primitive value which is returned by Eval.
+func typeOfValue(value interface{}) types.Type {
+	switch value.(type) {
+	case bool:
+		return types.Boolean
+	case string:
+		return types.String
+	case int64:
+		return types.Int
+	case float64:
+		return types.Float
+	}
+	return types.Nop
+}
+
+func TestTypeCheckUnaryOperator(t *testing.T) {
+	c := NewChecker()
+	testCases := []struct {
+		operator     UnaryOperator
+		expectedType types.Type
+	}{
+		{UnaryOperator{Symbol: "!", Exp: Boolean{Data: true}}, types.Boolean},
+		{UnaryOperator{Symbol: "!", Exp: Operator{Symbol: "<", FirstExp: Integer{Data: 1}, SecondExp: Integer{Data: 2}}}, types.Boolean},
+		{UnaryOperator{Symbol: "-", Exp: Integer{Data: 1}}, types.Int},
+		{UnaryOperator{Symbol: "-", Exp: Float{Data: 1.5}}, types.Float},
+		{UnaryOperator{Symbol: "-", Exp: UnaryOperator{Symbol: "-", Exp: Integer{Data: 1}}}, types.Int},
+
+		{UnaryOperator{Symbol: "!", Exp: Integer{Data: 1}}, types.Nop},
+		{UnaryOperator{Symbol: "!", Exp: String{Data: "abc"}}, types.Nop},
+		{UnaryOperator{Symbol: "-", Exp: Boolean{Data: true}}, types.Nop},
+		{UnaryOperator{Symbol: "-", Exp: String{Data: "abc"}}, types.Nop},
+	}
+
+	for _, testCase := range testCases {
+		if tipe, _ := c.TypeCheckUnaryOperator(testCase.operator); tipe != testCase.expectedType {
+			t.Errorf(`expected type "%v" but got type "%v" after input of "%+v"`, testCase.expectedType, tipe, testCase.operator)
+		}
+	}
+
+	testTypeCheckExpr(t, c, If{Condition: UnaryOperator{Symbol: "!", Exp: Boolean{Data: false}}, Body: Block{}})
+}
+
 func TestTypeCheckFunctionCall(t *testing.T) {
-	testTypeCheckFunctionCall(t, FunctionCall{Name: "println", Argument: String{Data: "Hello World"}}, NopType)
-	testTypeCheckFunctionCall(t, FunctionCall{Name: "readln", Argument: Nop{}}, StringType)
+	c := NewChecker()
+	testTypeCheckFunctionCall(t, c, FunctionCall{Name: "println", Arguments: []Expr{String{Data: "Hello World"}}}, types.Nop)
+	testTypeCheckFunctionCall(t, c, FunctionCall{Name: "readln", Arguments: []Expr{}}, types.String)
+
+	testTypeCheckFunctionCallNegative(t, c, FunctionCall{Name: "readln", Arguments: []Expr{String{Data: "ABC"}}})
+	testTypeCheckFunctionCallNegative(t, c, FunctionCall{Name: "println", Arguments: []Expr{}})
+	testTypeCheckFunctionCallNegative(t, c, FunctionCall{Name: "erfunden", Arguments: []Expr{String{Data: "ABC"}}})
+	testTypeCheckFunctionCall(t, c, FunctionCall{Name: "substr", Arguments: []Expr{String{Data: "abc"}, Integer{Data: 1}, Integer{Data: 3}}}, types.String)
+	testTypeCheckFunctionCallNegative(t, c, FunctionCall{Name: "substr", Arguments: []Expr{String{Data: "abc"}, Integer{Data: 1}}})
+	testTypeCheckFunctionCallNegative(t, c, FunctionCall{Name: "substr", Arguments: []Expr{String{Data: "abc"}, Integer{Data: 1}, Float{Data: 2.5}}})
+
+	// the diagnostics of the builtins point at the wrong argument
+	_, diagnostics := c.TypeCheckFunctionCall(FunctionCall{Name: "append", Arguments: []Expr{List{ElemType: types.Nop, Elems: []Expr{Integer{Data: 1}}}, String{Data: "x"}}})
+	if len(diagnostics) != 1 || diagnostics[0].Expected != types.Int || diagnostics[0].Actual != types.String {
+		t.Errorf("got diagnostics %v", diagnostics)
+	}
+	_, diagnostics = c.TypeCheckFunctionCall(FunctionCall{Name: "len", Arguments: []Expr{Integer{Data: 1}}})
+	if len(diagnostics) != 1 || diagnostics[0].Message != "Expected a list, a map or a String but got a value of type 'Int'" {
+		t.Errorf("got diagnostics %v", diagnostics)
+	}
+}
+
+func testTypeCheckFunctionCall(t *testing.T, c *Checker, function FunctionCall, expectedType types.Type) {
+	tipe, diagnostics := c.TypeCheckFunctionCall(function)
+
+	if len(diagnostics) != 0 || tipe != expectedType {
+		t.Errorf(`expected no diagnostics and type: "%v" but got diagnostics: %v, type: "%v" after input of "%+v"`, expectedType, diagnostics, tipe, function)
+	}
+}
+
+func testTypeCheckFunctionCallNegative(t *testing.T, c *Checker, function FunctionCall) {
+	tipe, diagnostics := c.TypeCheckFunctionCall(function)
+
+	if len(diagnostics) == 0 || tipe != types.Nop {
+		t.Errorf(`expected diagnostics and type: Nop but got diagnostics: %v, type: "%v" after input of "%+v"`, diagnostics, tipe, function)
+	}
+}
+
+func TestTypeCheckFunctionDecl(t *testing.T) {
+	c := NewChecker()
+	testTypeCheckExpr(t, c, FunctionDecl{
+		Name:    "add",
+		Params:  []Param{{Name: "a", Type: types.Int}, {Name: "b", Type: types.Int}},
+		Returns: types.Int,
+		Body: Block{Statements: []Expr{
+			Return{Expr: Operator{Symbol: "+", FirstExp: ReadVar{Name: "a"}, SecondExp: ReadVar{Name: "b"}}},
+		}},
+	})
+	testTypeCheckExpr(t, c, FunctionDecl{
+		Name:    "countdown",
+		Params:  []Param{{Name: "n", Type: types.Int}},
+		Returns: types.Nop,
+		Body: Block{Statements: []Expr{
+			If{
+				Condition: Operator{Symbol: "==", FirstExp: ReadVar{Name: "n"}, SecondExp: Integer{Data: 0}},
+				Body:      Block{Statements: []Expr{Return{Expr: Nop{}}}},
+			},
+			FunctionCall{Name: "countdown", Arguments: []Expr{Operator{Symbol: "-", FirstExp: ReadVar{Name: "n"}, SecondExp: Integer{Data: 1}}}},
+		}},
+	})
+	testTypeCheckExpr(t, c, WriteVar{Name: "sum", Expr: FunctionCall{Name: "add", Arguments: []Expr{Integer{Data: 1}, Integer{Data: 2}}}})
+	testTypeCheckExpr(t, c, FunctionCall{Name: "countdown", Arguments: []Expr{ReadVar{Name: "sum"}}})
+
+	// wrong arguments
+	testTypeCheckExprNegative(t, c, FunctionCall{Name: "add", Arguments: []Expr{Integer{Data: 1}}})
+	testTypeCheckExprNegative(t, c, FunctionCall{Name: "add", Arguments: []Expr{Integer{Data: 1}, String{Data: "2"}}})
+	// functions without a return type don't have a value
+	testTypeCheckExprNegative(t, c, WriteVar{Name: "x", Expr: FunctionCall{Name: "countdown", Arguments: []Expr{Integer{Data: 1}}}})
+	// declared twice
+	testTypeCheckExprNegative(t, c, FunctionDecl{Name: "add", Params: []Param{}, Returns: types.Nop, Body: Block{}})
+	testTypeCheckExprNegative(t, c, FunctionDecl{Name: "println", Params: []Param{}, Returns: types.Nop, Body: Block{}})
+	// not every path returns a value
+	testTypeCheckExprNegative(t, c, FunctionDecl{Name: "noReturn", Params: []Param{}, Returns: types.Int, Body: Block{}})
+	// wrong type of the returned value
+	testTypeCheckExprNegative(t, c, FunctionDecl{
+		Name:    "wrongReturn",
+		Params:  []Param{},
+		Returns: types.Int,
+		Body:    Block{Statements: []Expr{Return{Expr: String{Data: "abc"}}}},
+	})
+	// the body can't access variables from outside of the function
+	testTypeCheckExprNegative(t, c, FunctionDecl{
+		Name:    "outside",
+		Params:  []Param{},
+		Returns: types.Int,
+		Body:    Block{Statements: []Expr{Return{Expr: ReadVar{Name: "sum"}}}},
+	})
+	// return outside of a function
+	testTypeCheckExprNegative(t, c, Return{Expr: Nop{}})
+	// builtins can't be declared again
+	for _, name := range []string{"println", "keys", "substr"} {
+		testTypeCheckExprNegative(t, c, FunctionDecl{Name: name, Params: []Param{}, Returns: types.Nop, Body: Block{Statements: []Expr{}}})
+	}
+}
+
+func TestTypeCheckIf(t *testing.T) {
+	c := NewChecker()
+	testTypeCheckExpr(t, c, If{
+		Condition: Boolean{Data: true},
+		Body:      Block{Statements: []Expr{WriteVar{Name: "inBody", Expr: Integer{Data: 1}}}},
+		Else: &Block{Statements: []Expr{If{
+			Condition: Boolean{Data: false},
+			Body:      Block{Statements: []Expr{WriteVar{Name: "inElseIf", Expr: Integer{Data: 1}}}},
+			Else:      &Block{Statements: []Expr{WriteVar{Name: "inElse", Expr: Integer{Data: 1}}}},
+		}}},
+	})
+
+	// every branch has its own scope
+	testTypeCheckExprNegative(t, c, If{
+		Condition: Boolean{Data: true},
+		Body:      Block{Statements: []Expr{WriteVar{Name: "x", Expr: Integer{Data: 1}}}},
+		Else:      &Block{Statements: []Expr{WriteVar{Name: "y", Expr: ReadVar{Name: "x"}}}},
+	})
+	testTypeCheckExprNegative(t, c, If{
+		Condition: Boolean{Data: true},
+		Body:      Block{Statements: []Expr{}},
+		Else:      &Block{Statements: []Expr{FunctionCall{Name: "println", Arguments: []Expr{Integer{Data: 1}}}}},
+	})
+}
+
+func TestTypeCheckFunctionDecl_ifElseReturns(t *testing.T) {
+	c := NewChecker()
+	returnIn := func(value int64) Block {
+		return Block{Statements: []Expr{Return{Expr: Integer{Data: value}}}}
+	}
+	decl := func(name string, ifExpr If) FunctionDecl {
+		return FunctionDecl{Name: name, Params: []Param{{Name: "n", Type: types.Int}}, Returns: types.Int, Body: Block{
+			Statements: []Expr{ifExpr},
+		}}
+	}
+	isZero := Operator{Symbol: "==", FirstExp: ReadVar{Name: "n"}, SecondExp: Integer{Data: 0}}
+	isOne := Operator{Symbol: "==", FirstExp: ReadVar{Name: "n"}, SecondExp: Integer{Data: 1}}
+
+	elseBlock := returnIn(2)
+	elseIfBlock := Block{Statements: []Expr{If{Condition: isOne, Body: returnIn(1), Else: &elseBlock}}}
+	testTypeCheckExpr(t, c, decl("ifElse", If{Condition: isZero, Body: returnIn(0), Else: &elseBlock}))
+	testTypeCheckExpr(t, c, decl("elseIf", If{Condition: isZero, Body: returnIn(0), Else: &elseIfBlock}))
 
-	testTypeCheckFunctionCallNegative(t, FunctionCall{Name: "readln", Argument: String{Data: "ABC"}})
-	testTypeCheckFunctionCallNegative(t, FunctionCall{Name: "println", Argument: Nop{}})
-	testTypeCheckFunctionCallNegative(t, FunctionCall{Name: "erfunden", Argument: String{Data: "ABC"}})
+	missingElse := Block{Statements: []Expr{If{Condition: isOne, Body: returnIn(1)}}}
+	testTypeCheckExprNegative(t, c, decl("noElse", If{Condition: isZero, Body: returnIn(0)}))
+	testTypeCheckExprNegative(t, c, decl("noFinalElse", If{Condition: isZero, Body: returnIn(0), Else: &missingElse}))
 }
 
-func testTypeCheckFunctionCall(t *testing.T, function FunctionCall, expectedType Type) {
-	valid, tipe := TypeCheckFunctionCall(function)
+func TestTypeCheckLoopControl(t *testing.T) {
+	c := NewChecker()
+	body := func(stmts ...Expr) Block {
+		return Block{Statements: stmts}
+	}
+
+	testTypeCheckExpr(t, c, While{Condition: Boolean{Data: true}, Body: body(Break{}, Continue{})})
+	testTypeCheckExpr(t, c, For{Init: Nop{}, Condition: Boolean{Data: true}, Advancement: Nop{}, Body: body(
+		If{Condition: Boolean{Data: true}, Body: body(Break{})},
+	)})
+	testTypeCheckExpr(t, c, While{Label: "outer", Condition: Boolean{Data: true}, Body: body(
+		While{Condition: Boolean{Data: true}, Body: body(Break{Label: "outer"}, Continue{Label: "outer"})},
+	)})
+
+	// outside of a loop
+	testTypeCheckExprNegative(t, c, Break{})
+	testTypeCheckExprNegative(t, c, Continue{})
+	testTypeCheckExprNegative(t, c, If{Condition: Boolean{Data: true}, Body: body(Break{})})
+	// unknown label
+	testTypeCheckExprNegative(t, c, While{Label: "outer", Condition: Boolean{Data: true}, Body: body(Break{Label: "inner"})})
+	// label used twice
+	testTypeCheckExprNegative(t, c, While{Label: "outer", Condition: Boolean{Data: true}, Body: body(
+		While{Label: "outer", Condition: Boolean{Data: true}, Body: body()},
+	)})
+	// break can't leave a function
+	testTypeCheckExprNegative(t, c, While{Condition: Boolean{Data: true}, Body: body(
+		FunctionDecl{Name: "breaking", Params: []Param{}, Returns: types.Nop, Body: body(Break{})},
+	)})
+	// the condition has to be a Boolean
+	testTypeCheckExprNegative(t, c, While{Condition: Integer{Data: 1}, Body: body()})
+}
+
+func TestTypeCheckList(t *testing.T) {
+	c := NewChecker()
+	ints := List{ElemType: types.Nop, Elems: []Expr{Integer{Data: 1}, Integer{Data: 2}}}
+	testCases := []struct {
+		expr         Expr
+		expectedType types.Type
+	}{
+		{ints, types.List{Elem: types.Int}},
+		{List{ElemType: types.String, Elems: []Expr{}}, types.List{Elem: types.String}},
+		{List{ElemType: types.Float, Elems: []Expr{Float{Data: 1}}}, types.List{Elem: types.Float}},
+		{List{ElemType: types.Nop, Elems: []Expr{ints, List{ElemType: types.Int, Elems: []Expr{}}}}, types.List{Elem: types.List{Elem: types.Int}}},
+		{Index{Exp: ints, Index: Integer{Data: 0}}, types.Int},
+		{FunctionCall{Name: "len", Arguments: []Expr{ints}}, types.Int},
+		{FunctionCall{Name: "len", Arguments: []Expr{String{Data: "abc"}}}, types.Int},
+		{FunctionCall{Name: "append", Arguments: []Expr{ints, Integer{Data: 3}}}, types.List{Elem: types.Int}},
+
+		// the type of empty lists is unknown
+		{List{ElemType: types.Nop, Elems: []Expr{}}, types.Nop},
+		// elements with different types
+		{List{ElemType: types.Nop, Elems: []Expr{Integer{Data: 1}, Float{Data: 2}}}, types.Nop},
+		{List{ElemType: types.String, Elems: []Expr{Integer{Data: 1}}}, types.Nop},
+		{Index{Exp: ints, Index: String{Data: "0"}}, types.Nop},
+		{Index{Exp: Integer{Data: 1}, Index: Integer{Data: 0}}, types.Nop},
+		{FunctionCall{Name: "len", Arguments: []Expr{Integer{Data: 1}}}, types.Nop},
+		{FunctionCall{Name: "append", Arguments: []Expr{ints, String{Data: "3"}}}, types.Nop},
+		{FunctionCall{Name: "append", Arguments: []Expr{ints}}, types.Nop},
+	}
+
+	for _, testCase := range testCases {
+		if tipe, _ := c.TypeCheckRightExpr(testCase.expr); tipe != testCase.expectedType {
+			t.Errorf(`expected type "%v" but got type "%v" after input of "%+v"`, testCase.expectedType, tipe, testCase.expr)
+		}
+	}
+
+	testTypeCheckExpr(t, c, WriteVar{Name: "xs", Expr: ints})
+	testTypeCheckExpr(t, c, WriteIndex{Exp: ReadVar{Name: "xs"}, Index: Integer{Data: 0}, Value: Integer{Data: 5}})
+	testTypeCheckExpr(t, c, FunctionDecl{
+		Name:    "first",
+		Params:  []Param{{Name: "xs", Type: types.List{Elem: types.Int}}},
+		Returns: types.Int,
+		Body:    Block{Statements: []Expr{Return{Expr: Index{Exp: ReadVar{Name: "xs"}, Index: Integer{Data: 0}}}}},
+	})
+	testTypeCheckExpr(t, c, FunctionCall{Name: "first", Arguments: []Expr{ReadVar{Name: "xs"}}})
+
+	testTypeCheckExprNegative(t, c, WriteIndex{Exp: ReadVar{Name: "xs"}, Index: Integer{Data: 0}, Value: String{Data: "5"}})
+	testTypeCheckExprNegative(t, c, FunctionCall{Name: "first", Arguments: []Expr{List{ElemType: types.String, Elems: []Expr{}}}})
+}
+
+func TestTypeCheckMap(t *testing.T) {
+	c := NewChecker()
+	counts := Map{KeyType: types.String, ValueType: types.Int, Entries: []MapEntry{
+		{Key: String{Data: "a"}, Value: Integer{Data: 1}},
+	}}
+	testCases := []struct {
+		expr         Expr
+		expectedType types.Type
+	}{
+		{counts, types.Map{Key: types.String, Value: types.Int}},
+		{Map{KeyType: types.Int, ValueType: types.List{Elem: types.String}, Entries: []MapEntry{}}, types.Map{Key: types.Int, Value: types.List{Elem: types.String}}},
+		{Index{Exp: counts, Index: String{Data: "a"}}, types.Int},
+		{FunctionCall{Name: "len", Arguments: []Expr{counts}}, types.Int},
+		{FunctionCall{Name: "has", Arguments: []Expr{counts, String{Data: "a"}}}, types.Boolean},
+		{FunctionCall{Name: "delete", Arguments: []Expr{counts, String{Data: "a"}}}, types.Nop},
+		{FunctionCall{Name: "keys", Arguments: []Expr{counts}}, types.List{Elem: types.String}},
+
+		// wrong types of keys or values
+		{Map{KeyType: types.String, ValueType: types.Int, Entries: []MapEntry{{Key: Integer{Data: 1}, Value: Integer{Data: 1}}}}, types.Nop},
+		{Map{KeyType: types.String, ValueType: types.Int, Entries: []MapEntry{{Key: String{Data: "a"}, Value: Float{Data: 1}}}}, types.Nop},
+		{Index{Exp: counts, Index: Integer{Data: 0}}, types.Nop},
+		{FunctionCall{Name: "has", Arguments: []Expr{counts, Integer{Data: 1}}}, types.Nop},
+		{FunctionCall{Name: "keys", Arguments: []Expr{List{ElemType: types.String, Elems: []Expr{}}}}, types.Nop},
+		// lists and maps can't be keys
+		{Map{KeyType: types.List{Elem: types.Int}, ValueType: types.Int, Entries: []MapEntry{}}, types.Nop},
+		{List{ElemType: types.Map{Key: types.Map{Key: types.Int, Value: types.Int}, Value: types.Int}, Elems: []Expr{}}, types.Nop},
+	}
+
+	for _, testCase := range testCases {
+		if tipe, _ := c.TypeCheckRightExpr(testCase.expr); tipe != testCase.expectedType {
+			t.Errorf(`expected type "%v" but got type "%v" after input of "%+v"`, testCase.expectedType, tipe, testCase.expr)
+		}
+	}
+
+	testTypeCheckExpr(t, c, WriteVar{Name: "counts", Expr: counts})
+	testTypeCheckExpr(t, c, WriteIndex{Exp: ReadVar{Name: "counts"}, Index: String{Data: "b"}, Value: Integer{Data: 2}})
+	testTypeCheckExpr(t, c, FunctionCall{Name: "delete", Arguments: []Expr{ReadVar{Name: "counts"}, String{Data: "b"}}})
+
+	testTypeCheckExprNegative(t, c, WriteIndex{Exp: ReadVar{Name: "counts"}, Index: String{Data: "b"}, Value: String{Data: "2"}})
+	testTypeCheckExprNegative(t, c, FunctionDecl{Name: "badKey", Params: []Param{{Name: "m", Type: types.Map{Key: types.List{Elem: types.Int}, Value: types.Int}}}, Returns: types.Nop, Body: Block{}})
+}
+
+func TestTypeCheckStruct(t *testing.T) {
+	c := NewChecker()
+	testTypeCheckExpr(t, c, StructDecl{Name: "Vec", Fields: []FieldDecl{{Name: "x", Type: types.Int}, {Name: "y", Type: types.Int}}})
+	testTypeCheckExpr(t, c, StructDecl{Name: "Tree", Fields: []FieldDecl{
+		{Name: "pos", Type: types.Struct{Name: "Vec"}},
+		{Name: "children", Type: types.List{Elem: types.Struct{Name: "Tree"}}},
+	}})
+	vec := Struct{Name: "Vec", Fields: []FieldValue{{Name: "y", Value: Integer{Data: 2}}, {Name: "x", Value: Integer{Data: 1}}}}
+	tree := Struct{Name: "Tree", Fields: []FieldValue{
+		{Name: "pos", Value: vec},
+		{Name: "children", Value: List{ElemType: types.Struct{Name: "Tree"}, Elems: []Expr{}}},
+	}}
+
+	testCases := []struct {
+		expr         Expr
+		expectedType types.Type
+	}{
+		{vec, types.Struct{Name: "Vec"}},
+		{tree, types.Struct{Name: "Tree"}},
+		{Field{Exp: vec, Name: "x"}, types.Int},
+		{Field{Exp: Field{Exp: tree, Name: "pos"}, Name: "y"}, types.Int},
+		{Field{Exp: tree, Name: "children"}, types.List{Elem: types.Struct{Name: "Tree"}}},
+
+		// unknown struct or field
+		{Struct{Name: "Unknown", Fields: []FieldValue{}}, types.Nop},
+		{Field{Exp: vec, Name: "z"}, types.Nop},
+		{Field{Exp: Integer{Data: 1}, Name: "x"}, types.Nop},
+		// missing, duplicate or wrongly typed fields
+		{Struct{Name: "Vec", Fields: []FieldValue{{Name: "x", Value: Integer{Data: 1}}}}, types.Nop},
+		{Struct{Name: "Vec", Fields: []FieldValue{{Name: "x", Value: Integer{Data: 1}}, {Name: "x", Value: Integer{Data: 1}}}}, types.Nop},
+		{Struct{Name: "Vec", Fields: []FieldValue{{Name: "x", Value: Integer{Data: 1}}, {Name: "y", Value: Float{Data: 1}}}}, types.Nop},
+		{List{ElemType: types.Struct{Name: "Unknown"}, Elems: []Expr{}}, types.Nop},
+	}
+
+	for _, testCase := range testCases {
+		if tipe, _ := c.TypeCheckRightExpr(testCase.expr); tipe != testCase.expectedType {
+			t.Errorf(`expected type "%v" but got type "%v" after input of "%+v"`, testCase.expectedType, tipe, testCase.expr)
+		}
+	}
+
+	testTypeCheckExpr(t, c, WriteVar{Name: "v", Expr: vec})
+	testTypeCheckExpr(t, c, WriteField{Exp: ReadVar{Name: "v"}, Name: "x", Value: Integer{Data: 3}})
+	testTypeCheckExpr(t, c, FunctionDecl{
+		Name:    "origin",
+		Params:  []Param{},
+		Returns: types.Struct{Name: "Vec"},
+		Body: Block{Statements: []Expr{Return{Expr: Struct{Name: "Vec", Fields: []FieldValue{
+			{Name: "x", Value: Integer{Data: 0}},
+			{Name: "y", Value: Integer{Data: 0}},
+		}}}}},
+	})
+
+	testTypeCheckExprNegative(t, c, WriteField{Exp: ReadVar{Name: "v"}, Name: "x", Value: String{Data: "3"}})
+	testTypeCheckExprNegative(t, c, WriteField{Exp: ReadVar{Name: "v"}, Name: "z", Value: Integer{Data: 3}})
+	// declared twice, duplicate fields, unknown types or the name of a built in type
+	testTypeCheckExprNegative(t, c, StructDecl{Name: "Vec", Fields: []FieldDecl{}})
+	testTypeCheckExprNegative(t, c, StructDecl{Name: "Twice", Fields: []FieldDecl{{Name: "a", Type: types.Int}, {Name: "a", Type: types.Float}}})
+	testTypeCheckExprNegative(t, c, StructDecl{Name: "Unknown", Fields: []FieldDecl{{Name: "a", Type: types.Struct{Name: "Nope"}}}})
+	testTypeCheckExprNegative(t, c, StructDecl{Name: "Int", Fields: []FieldDecl{}})
+	testTypeCheckExprNegative(t, c, FunctionDecl{Name: "unknownParam", Params: []Param{{Name: "a", Type: types.Struct{Name: "Nope"}}}, Returns: types.Nop, Body: Block{}})
+}
+
+func TestTypeCheckBlock_diagnostics(t *testing.T) {
+	block, err := ParseFile("test.mbs", `a = 1;
+b = a + "x";
+println(unknown);
+if (a) {
+	c = nope(1);
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Diagnostic{
+		{Message: "The operator '+' can't be used with the types 'Int' and 'String'", Expected: types.Nop, Actual: types.Nop},
+		{Message: "Unknown variable 'unknown'", Expected: types.Nop, Actual: types.Nop},
+		{Message: "Expected a value of type 'Boolean' but got a value of type 'Int'", Expected: types.Boolean, Actual: types.Int},
+		{Message: "Unknown function 'nope'", Expected: types.Nop, Actual: types.Nop},
+	}
+	positions := []string{"test.mbs:2:5", "test.mbs:3:9", "test.mbs:4:5", "test.mbs:5:6"}
+
+	// every error in the block is reported, not only the first one
+	diagnostics := NewChecker().TypeCheckBlock(block)
+	if len(diagnostics) != len(expected) {
+		t.Fatalf("got %d diagnostics %v wanted %d", len(diagnostics), diagnostics, len(expected))
+	}
+	for i, d := range diagnostics {
+		if d.Message != expected[i].Message || d.Expected != expected[i].Expected || d.Actual != expected[i].Actual {
+			t.Errorf(`got (%s, %v, %v) wanted (%s, %v, %v)`, d.Message, d.Expected, d.Actual, expected[i].Message, expected[i].Expected, expected[i].Actual)
+		}
+		if d.Span.Start.String() != positions[i] || d.Span != d.Expr.Pos() {
+			t.Errorf(`got the position %v wanted %s`, d.Span.Start, positions[i])
+		}
+	}
+}
 
-	if !valid || tipe != expectedType {
-		t.Errorf(`expected valid: "true", type: "%v" but got valid: "%v", type: "%v" after input of "%+v"`, expectedType, valid, tipe, function)
+func TestTypeCheckBlock_followUpErrors(t *testing.T) {
+	testCase := func(code string, expected ...string) {
+		t.Run(code, func(t *testing.T) {
+			block, err := ParseCode(code)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var messages []string
+			for _, d := range NewChecker().TypeCheckBlock(block) {
+				messages = append(messages, d.Message)
+			}
+			if diff := cmp.Diff(expected, messages); diff != "" {
+				t.Error(diff)
+			}
+		})
 	}
+
+	// a variable whose value has a type error is still declared, its uses aren't reported again
+	testCase(`a = 1 + "x"; b = a + 1; c = a * 2;`, "The operator '+' can't be used with the types 'Int' and 'String'")
+	testCase(`a = -"x"; if (a) { a = 2; } println(a); d = b;`, "The operator '-' can't be used with the type 'String'", "Unknown variable 'b'")
+	testCase(`a = [1, "x"]; b = a[0] + 1; b = "y" * 2;`, "Expected a value of type 'Int' but got a value of type 'String'",
+		"The operator '*' can't be used with the types 'String' and 'Int'")
+}
+
+func TestTypeCheckBlock_scopes(t *testing.T) {
+	testCase := func(code string, valid bool) {
+		t.Run(code, func(t *testing.T) {
+			block, err := ParseCode(code)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diagnostics := NewChecker().TypeCheckBlock(block); (len(diagnostics) == 0) != valid {
+				t.Errorf(`got diagnostics %v wanted valid: %v`, diagnostics, valid)
+			}
+		})
+	}
+
+	// outer variables can be overwritten inside of a block but keep their type
+	testCase(`a = 1; while (a < 3) { a = a + 1; } b = a + 1;`, true)
+	testCase(`a = 1; if (true) { a = 2; } else { a = 3; }`, true)
+	testCase(`a = 1; if (true) { a = "x"; }`, false)
+	testCase(`a = 1; for (i = 0; i < 3; i = i + 1) { a = 1.5; }`, false)
+	// variables declared inside of a block or a for loop disappear after it
+	testCase(`if (true) { y = 1; } z = y;`, false)
+	testCase(`for (k = 0; k < 3; k = k + 1) {} z = k;`, false)
+	// the advancement runs before the condition, so it can't change the type of the loop variable
+	testCase(`for (i = 0; i < 3; i = "x") { println("it"); }`, false)
+	testCase(`i = 0; for (; i < 3; i = 1.5) {}`, false)
+	testCase(`for (i = 0; i < 3; i = i + 1) {}`, true)
+	testCase(`if (true) { y = 1; } y = "x"; z = y + "y";`, true)
+	// variables of the same scope can still get a new type
+	testCase(`a = 1; a = "x"; b = a + "y";`, true)
 }
 
-func testTypeCheckFunctionCallNegative(t *testing.T, function FunctionCall) {
-	valid, tipe := TypeCheckFunctionCall(function)
+func TestChecker_separateScripts(t *testing.T) {
+	check := func(c *Checker, code string) []Diagnostic {
+		block, err := ParseCode(code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c.TypeCheckBlock(block)
+	}
+
+	// every Checker starts without any declarations, so the same script can be checked again
+	script := `type P struct { x Int } func f() Int { return 1; } p = P{x: f()};`
+	for i := 0; i < 2; i++ {
+		if diagnostics := check(NewChecker(), script); diagnostics != nil {
+			t.Errorf("got diagnostics %v wanted none", diagnostics)
+		}
+	}
+
+	// the functions and structs of another script aren't known
+	if diagnostics := check(NewChecker(), `x = f();`); len(diagnostics) != 1 || diagnostics[0].Message != "Unknown function 'f'" {
+		t.Errorf("got diagnostics %v wanted an unknown function", diagnostics)
+	}
+	if diagnostics := check(NewChecker(), `p = P{x: 1};`); len(diagnostics) != 1 || diagnostics[0].Message != "Unknown struct 'P'" {
+		t.Errorf("got diagnostics %v wanted an unknown struct", diagnostics)
+	}
 
-	if valid || tipe != NopType {
-		t.Errorf(`expected valid: "false", type: Nop but got valid: "%v", type: "%v" after input of "%+v"`, valid, tipe, function)
+	// a Checker which is used again keeps the declarations of the scripts it checked before
+	c := NewChecker()
+	check(c, script)
+	if diagnostics := check(c, `q = P{x: f()};`); diagnostics != nil {
+		t.Errorf("got diagnostics %v wanted none", diagnostics)
 	}
 }
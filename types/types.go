@@ -0,0 +1,75 @@
+// Package types describes the types of the values in mbs. Lists, maps and structs have types which are built out of
+// other types, so their Types contain these types instead of encoding them in a name. Like in the source code, two
+// types are the same if they are built the same way, so Types can be compared with == and used as keys of maps.
+package types
+
+// Type is the type of a value, e.g. Int or map[String][]Int.
+type Type interface {
+	// String returns the name of the type as it is written in the source code.
+	String() string
+	// isType makes sure that only the types of this package are Types, all of them can be compared with ==.
+	isType()
+}
+
+// Basic is one of the types which are built into the language.
+type Basic string
+
+const (
+	Boolean Basic = "Boolean"
+	Int     Basic = "Int"
+	Float   Basic = "Float"
+	String  Basic = "String"
+	// Nop is used where there is no type, e.g. as the return type of functions which don't return a value.
+	Nop Basic = "Nop"
+	// Invalid is the type of variables whose value has a type error. It can't be written in the source code.
+	Invalid Basic = "Invalid"
+)
+
+func (b Basic) String() string {
+	return string(b)
+}
+
+func (b Basic) isType() {}
+
+// List is the type of a list whose elements are of the type Elem, e.g. []Int.
+type List struct {
+	Elem Type
+}
+
+func (l List) String() string {
+	return "[]" + l.Elem.String()
+}
+
+func (l List) isType() {}
+
+// Map is the type of a map which maps keys of the type Key to values of the type Value, e.g. map[String]Int.
+type Map struct {
+	Key   Type
+	Value Type
+}
+
+func (m Map) String() string {
+	return "map[" + m.Key.String() + "]" + m.Value.String()
+}
+
+func (m Map) isType() {}
+
+// Struct is the type of the struct which was declared with the name.
+type Struct struct {
+	Name string
+}
+
+func (s Struct) String() string {
+	return s.Name
+}
+
+func (s Struct) isType() {}
+
+// Lookup returns the built in type which has the name in the source code, e.g. Int for "Int".
+func Lookup(name string) (Type, bool) {
+	switch b := Basic(name); b {
+	case Boolean, Int, Float, String:
+		return b, true
+	}
+	return Nop, false
+}
@@ -0,0 +1,43 @@
+package types
+
+import "testing"
+
+func TestString(t *testing.T) {
+	testCase := func(tipe Type, expected string) {
+		if tipe.String() != expected {
+			t.Errorf("got %q wanted %q", tipe.String(), expected)
+		}
+	}
+
+	testCase(Int, "Int")
+	testCase(List{Elem: List{Elem: String}}, "[][]String")
+	testCase(Map{Key: String, Value: List{Elem: Float}}, "map[String][]Float")
+	testCase(Map{Key: Int, Value: Map{Key: Boolean, Value: Struct{Name: "Point"}}}, "map[Int]map[Boolean]Point")
+}
+
+func TestEqual(t *testing.T) {
+	testCase := func(a, b Type, equal bool) {
+		if (a == b) != equal {
+			t.Errorf("got %v == %v to be %v", a, b, !equal)
+		}
+	}
+
+	testCase(List{Elem: Int}, List{Elem: Int}, true)
+	testCase(Map{Key: String, Value: List{Elem: Struct{Name: "P"}}}, Map{Key: String, Value: List{Elem: Struct{Name: "P"}}}, true)
+	testCase(List{Elem: Int}, List{Elem: Float}, false)
+	testCase(Map{Key: String, Value: Int}, Map{Key: Int, Value: String}, false)
+	// a struct called like a built in type is a different type
+	testCase(Struct{Name: "Int"}, Int, false)
+	testCase(List{Elem: Int}, Map{Key: Int, Value: Int}, false)
+}
+
+func TestLookup(t *testing.T) {
+	if tipe, ok := Lookup("Int"); !ok || tipe != Int {
+		t.Errorf("got %v, %v wanted Int", tipe, ok)
+	}
+	for _, name := range []string{"Integer", "Nop", "Point", "[]Int"} {
+		if tipe, ok := Lookup(name); ok {
+			t.Errorf("got %v for %q wanted no type", tipe, name)
+		}
+	}
+}